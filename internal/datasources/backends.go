@@ -0,0 +1,116 @@
+package datasources
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/camptocamp/terraform-provider-puppetdb/internal/log"
+	"github.com/camptocamp/terraform-provider-puppetdb/internal/provider"
+	"github.com/camptocamp/terraform-provider-puppetdb/internal/puppetdb"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+type fanOutResult[T any] struct {
+	backend string
+	value   T
+	err     error
+}
+
+// fanOut runs the query against every backend concurrently and returns the
+// results ordered by backend name.
+func fanOut[T any](ctx context.Context, backends map[string]*puppetdb.Client, query func(client *puppetdb.Client) (T, error)) []fanOutResult[T] {
+	names := backendNames(backends)
+	results := make([]fanOutResult[T], len(names))
+
+	var wg sync.WaitGroup
+
+	for i, name := range names {
+		wg.Add(1)
+
+		go func(i int, name string) {
+			defer wg.Done()
+
+			value, err := query(backends[name])
+
+			tflog.Trace(ctx, "Queried backend", log.MergeFields(log.BackendsFields([]string{name}), log.ErrorField(err)))
+
+			results[i] = fanOutResult[T]{
+				backend: name,
+				value:   value,
+				err:     err,
+			}
+		}(i, name)
+	}
+
+	wg.Wait()
+
+	return results
+}
+
+// selectBackends returns the backends listed in names, or all of them when
+// names is null.
+func selectBackends(ctx context.Context, p *provider.Provider, names types.List) (map[string]*puppetdb.Client, diag.Diagnostics) {
+	backends := p.Backends()
+
+	if names.IsNull() {
+		return backends, nil
+	}
+
+	var selectedNames []string
+
+	diags := names.ElementsAs(ctx, &selectedNames, false)
+
+	if diags.HasError() {
+		return nil, diags
+	}
+
+	selected := make(map[string]*puppetdb.Client, len(selectedNames))
+
+	for _, name := range selectedNames {
+		client, ok := backends[name]
+
+		if !ok {
+			diags.AddAttributeError(path.Root("backends"), "Unknown backend", "Backend "+name+" is not configured on the provider.")
+
+			continue
+		}
+
+		selected[name] = client
+	}
+
+	return selected, diags
+}
+
+// selectBackend returns the backend called name, setting name to the default
+// backend, which is the provider's own connection, when null.
+func selectBackend(p *provider.Provider, name *types.String) (*puppetdb.Client, diag.Diagnostics) {
+	var diags diag.Diagnostics
+
+	if name.IsNull() {
+		*name = types.StringValue(provider.DefaultBackend)
+	}
+
+	client, ok := p.Backends()[name.ValueString()]
+
+	if !ok {
+		diags.AddAttributeError(path.Root("backend"), "Unknown backend", "Backend "+name.ValueString()+" is not configured on the provider.")
+	}
+
+	return client, diags
+}
+
+func backendNames(backends map[string]*puppetdb.Client) []string {
+	names := make([]string, 0, len(backends))
+
+	for name := range backends {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
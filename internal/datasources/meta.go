@@ -0,0 +1,100 @@
+package datasources
+
+import (
+	"context"
+
+	"github.com/camptocamp/terraform-provider-puppetdb/internal/provider"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+type Meta struct {
+	provider *provider.Provider
+}
+
+type MetaModel struct {
+	Backend    types.String `tfsdk:"backend"`
+	Version    types.String `tfsdk:"version"`
+	ServerTime types.String `tfsdk:"server_time"`
+}
+
+func (d *Meta) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_meta"
+}
+
+func (d *Meta) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Attributes: map[string]schema.Attribute{
+			"backend": schema.StringAttribute{
+				Optional: true,
+				Computed: true,
+			},
+			"version": schema.StringAttribute{
+				Computed: true,
+			},
+			"server_time": schema.StringAttribute{
+				Computed: true,
+			},
+		},
+	}
+}
+
+func (d *Meta) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var state MetaModel
+
+	resp.Diagnostics.Append(req.Config.Get(ctx, &state)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	client, diags := selectBackend(d.provider, &state.Backend)
+
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	version, err := client.Version(ctx)
+
+	if err != nil {
+		resp.Diagnostics.Append(provider.ErrorDiagnostic("Failed to get PuppetDB version", err))
+
+		return
+	}
+
+	serverTime, err := client.ServerTime(ctx)
+
+	if err != nil {
+		resp.Diagnostics.Append(provider.ErrorDiagnostic("Failed to get PuppetDB server time", err))
+
+		return
+	}
+
+	state.Version = types.StringValue(version)
+	state.ServerTime = types.StringValue(serverTime)
+
+	tflog.Trace(ctx, "Queried PuppetDB metadata", map[string]any{
+		"backend": state.Backend.ValueString(),
+		"version": version,
+	})
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, state)...)
+}
+
+func NewMeta(p *provider.Provider) datasource.DataSource {
+	d := &Meta{
+		provider: p,
+	}
+
+	var _ datasource.DataSource = d
+
+	return d
+}
+
+func init() {
+	dataSources = append(dataSources, NewMeta)
+}
@@ -0,0 +1,121 @@
+package datasources
+
+import (
+	"context"
+
+	"github.com/camptocamp/terraform-provider-puppetdb/internal/provider"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+type Metrics struct {
+	provider *provider.Provider
+}
+
+type MetricsModel struct {
+	Backend types.String `tfsdk:"backend"`
+	MBeans  types.List   `tfsdk:"mbeans"`
+	Metrics types.Map    `tfsdk:"metrics"`
+}
+
+func (d *Metrics) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_metrics"
+}
+
+func (d *Metrics) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Attributes: map[string]schema.Attribute{
+			"backend": schema.StringAttribute{
+				Optional: true,
+				Computed: true,
+			},
+			"mbeans": schema.ListAttribute{
+				ElementType: types.StringType,
+				Required:    true,
+			},
+			"metrics": schema.MapAttribute{
+				ElementType: types.MapType{
+					ElemType: types.StringType,
+				},
+				Computed: true,
+			},
+		},
+	}
+}
+
+func (d *Metrics) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var state MetricsModel
+
+	resp.Diagnostics.Append(req.Config.Get(ctx, &state)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	client, diags := selectBackend(d.provider, &state.Backend)
+
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if err := client.RequireVersion(ctx, "The puppetdb_metrics data source", "6.0.0"); err != nil {
+		resp.Diagnostics.Append(provider.ErrorDiagnostic("Failed to read PuppetDB metrics", err))
+
+		return
+	}
+
+	var names []string
+
+	resp.Diagnostics.Append(state.MBeans.ElementsAs(ctx, &names, false)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	metrics := make(map[string]map[string]string, len(names))
+
+	for _, name := range names {
+		attributes, err := client.ReadMBean(ctx, name)
+
+		if err != nil {
+			resp.Diagnostics.Append(provider.ErrorDiagnostic("Failed to read PuppetDB metrics", err))
+
+			return
+		}
+
+		metrics[name] = attributes
+	}
+
+	state.Metrics, diags = types.MapValueFrom(ctx, types.MapType{ElemType: types.StringType}, metrics)
+
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	tflog.Trace(ctx, "Read PuppetDB metrics", map[string]any{
+		"backend": state.Backend.ValueString(),
+		"mbeans":  names,
+	})
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, state)...)
+}
+
+func NewMetrics(p *provider.Provider) datasource.DataSource {
+	d := &Metrics{
+		provider: p,
+	}
+
+	var _ datasource.DataSource = d
+
+	return d
+}
+
+func init() {
+	dataSources = append(dataSources, NewMetrics)
+}
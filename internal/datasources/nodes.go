@@ -0,0 +1,200 @@
+package datasources
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/camptocamp/terraform-provider-puppetdb/internal/log"
+	"github.com/camptocamp/terraform-provider-puppetdb/internal/provider"
+	"github.com/camptocamp/terraform-provider-puppetdb/internal/puppetdb"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+type Nodes struct {
+	provider *provider.Provider
+}
+
+type NodesModel struct {
+	Query    types.String `tfsdk:"query"`
+	Backends types.List   `tfsdk:"backends"`
+	Nodes    []NodeModel  `tfsdk:"nodes"`
+}
+
+type NodeModel struct {
+	Backend             types.String `tfsdk:"backend"`
+	CertificateName     types.String `tfsdk:"certname"`
+	Deactivated         types.String `tfsdk:"deactivated"`
+	Expired             types.String `tfsdk:"expired"`
+	CachedCatalogStatus types.String `tfsdk:"cached_catalog_status"`
+	CatalogEnvironment  types.String `tfsdk:"catalog_environment"`
+	FactsEnvironment    types.String `tfsdk:"facts_environment"`
+	ReportEnvironment   types.String `tfsdk:"report_environment"`
+	CatalogTimestamp    types.String `tfsdk:"catalog_timestamp"`
+	FactsTimestamp      types.String `tfsdk:"facts_timestamp"`
+	ReportTimestamp     types.String `tfsdk:"report_timestamp"`
+	LatestReportStatus  types.String `tfsdk:"latest_report_status"`
+}
+
+func (d *Nodes) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_nodes"
+}
+
+func (d *Nodes) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Attributes: map[string]schema.Attribute{
+			"query": schema.StringAttribute{
+				Optional: true,
+			},
+			"backends": schema.ListAttribute{
+				ElementType: types.StringType,
+				Optional:    true,
+			},
+			"nodes": schema.ListNestedAttribute{
+				Computed: true,
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"backend": schema.StringAttribute{
+							Computed: true,
+						},
+						"certname": schema.StringAttribute{
+							Computed: true,
+						},
+						"deactivated": schema.StringAttribute{
+							Computed: true,
+						},
+						"expired": schema.StringAttribute{
+							Computed: true,
+						},
+						"cached_catalog_status": schema.StringAttribute{
+							Computed: true,
+						},
+						"catalog_environment": schema.StringAttribute{
+							Computed: true,
+						},
+						"facts_environment": schema.StringAttribute{
+							Computed: true,
+						},
+						"report_environment": schema.StringAttribute{
+							Computed: true,
+						},
+						"catalog_timestamp": schema.StringAttribute{
+							Computed: true,
+						},
+						"facts_timestamp": schema.StringAttribute{
+							Computed: true,
+						},
+						"report_timestamp": schema.StringAttribute{
+							Computed: true,
+						},
+						"latest_report_status": schema.StringAttribute{
+							Computed: true,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func (d *Nodes) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var state NodesModel
+
+	resp.Diagnostics.Append(req.Config.Get(ctx, &state)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	query := "query/v4/nodes"
+
+	if !state.Query.IsNull() {
+		var ast []any
+
+		err := json.Unmarshal([]byte(state.Query.ValueString()), &ast)
+
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("query"), "Invalid query", "The query must be a PuppetDB AST query encoded as JSON. Reason: "+err.Error())
+
+			return
+		}
+
+		query, err = puppetdb.QueryPath(query, ast)
+
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("query"), "Invalid query", "Reason: "+err.Error())
+
+			return
+		}
+	}
+
+	backends, diags := selectBackends(ctx, d.provider, state.Backends)
+
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	results := fanOut(ctx, backends, func(client *puppetdb.Client) ([]puppetdb.Node, error) {
+		var nodes []puppetdb.Node
+
+		err := client.Do(ctx, query, "GET", nil, &nodes)
+
+		return nodes, err
+	})
+
+	state.Nodes = []NodeModel{}
+
+	for _, result := range results {
+		if result.err != nil {
+			resp.Diagnostics.Append(provider.ErrorDiagnostic("Failed to query nodes from backend "+result.backend, result.err))
+
+			continue
+		}
+
+		for _, node := range result.value {
+			state.Nodes = append(state.Nodes, NodeModel{
+				Backend:             types.StringValue(result.backend),
+				CertificateName:     types.StringValue(node.Certname),
+				Deactivated:         types.StringValue(node.Deactivated),
+				Expired:             types.StringValue(node.Expired),
+				CachedCatalogStatus: types.StringValue(node.CachedCatalogStatus),
+				CatalogEnvironment:  types.StringValue(node.CatalogEnvironment),
+				FactsEnvironment:    types.StringValue(node.FactsEnvironment),
+				ReportEnvironment:   types.StringValue(node.ReportEnvironment),
+				CatalogTimestamp:    types.StringValue(node.CatalogTimestamp),
+				FactsTimestamp:      types.StringValue(node.FactsTimestamp),
+				ReportTimestamp:     types.StringValue(node.ReportTimestamp),
+				LatestReportStatus:  types.StringValue(node.LatestReportStatus),
+			})
+		}
+	}
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	tflog.Trace(ctx, "Queried nodes", log.MergeFields(log.BackendsFields(backendNames(backends)), map[string]any{
+		"count": len(state.Nodes),
+	}))
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, state)...)
+}
+
+func NewNodes(p *provider.Provider) datasource.DataSource {
+	d := &Nodes{
+		provider: p,
+	}
+
+	var _ datasource.DataSource = d
+
+	return d
+}
+
+func init() {
+	dataSources = append(dataSources, NewNodes)
+}
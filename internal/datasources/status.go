@@ -0,0 +1,135 @@
+package datasources
+
+import (
+	"context"
+
+	"github.com/camptocamp/terraform-provider-puppetdb/internal/provider"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+type Status struct {
+	provider *provider.Provider
+}
+
+type StatusModel struct {
+	Backend         types.String `tfsdk:"backend"`
+	State           types.String `tfsdk:"state"`
+	Services        types.Map    `tfsdk:"services"`
+	ReadDBUp        types.Bool   `tfsdk:"read_db_up"`
+	WriteDBUp       types.Bool   `tfsdk:"write_db_up"`
+	QueueDepth      types.Int64  `tfsdk:"queue_depth"`
+	MaintenanceMode types.Bool   `tfsdk:"maintenance_mode"`
+}
+
+func (d *Status) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_status"
+}
+
+func (d *Status) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Attributes: map[string]schema.Attribute{
+			"backend": schema.StringAttribute{
+				Optional: true,
+				Computed: true,
+			},
+			"state": schema.StringAttribute{
+				Computed: true,
+			},
+			"services": schema.MapAttribute{
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"read_db_up": schema.BoolAttribute{
+				Computed: true,
+			},
+			"write_db_up": schema.BoolAttribute{
+				Computed: true,
+			},
+			"queue_depth": schema.Int64Attribute{
+				Computed: true,
+			},
+			"maintenance_mode": schema.BoolAttribute{
+				Computed: true,
+			},
+		},
+	}
+}
+
+func (d *Status) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var state StatusModel
+
+	resp.Diagnostics.Append(req.Config.Get(ctx, &state)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	client, diags := selectBackend(d.provider, &state.Backend)
+
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	services, err := client.Services(ctx)
+
+	if err != nil {
+		resp.Diagnostics.Append(provider.ErrorDiagnostic("Failed to get PuppetDB services status", err))
+
+		return
+	}
+
+	service, status, err := client.PuppetDBStatus(ctx)
+
+	if err != nil {
+		resp.Diagnostics.Append(provider.ErrorDiagnostic("Failed to get PuppetDB status", err))
+
+		return
+	}
+
+	serviceStates := make(map[string]string, len(services))
+
+	for name, service := range services {
+		serviceStates[name] = service.State
+	}
+
+	state.Services, diags = types.MapValueFrom(ctx, types.StringType, serviceStates)
+
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	state.State = types.StringValue(service.State)
+	state.ReadDBUp = types.BoolValue(status.ReadDBUp)
+	state.WriteDBUp = types.BoolValue(status.WriteDBUp)
+	state.QueueDepth = types.Int64Value(status.QueueDepth)
+	state.MaintenanceMode = types.BoolValue(status.MaintenanceMode)
+
+	tflog.Trace(ctx, "Queried PuppetDB status", map[string]any{
+		"backend":     state.Backend.ValueString(),
+		"state":       service.State,
+		"queue_depth": status.QueueDepth,
+	})
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, state)...)
+}
+
+func NewStatus(p *provider.Provider) datasource.DataSource {
+	d := &Status{
+		provider: p,
+	}
+
+	var _ datasource.DataSource = d
+
+	return d
+}
+
+func init() {
+	dataSources = append(dataSources, NewStatus)
+}
@@ -0,0 +1,7 @@
+package log
+
+func BackendsFields(backendNames []string) map[string]any {
+	return map[string]any{
+		"backends": backendNames,
+	}
+}
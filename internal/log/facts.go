@@ -0,0 +1,7 @@
+package log
+
+func FactsFields(factNames []string) map[string]any {
+	return map[string]any{
+		"fact_names": factNames,
+	}
+}
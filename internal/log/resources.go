@@ -0,0 +1,7 @@
+package log
+
+func ResourcesFields(resourceReferences []string) map[string]any {
+	return map[string]any{
+		"resources": resourceReferences,
+	}
+}
@@ -0,0 +1,105 @@
+package provider
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"time"
+
+	"github.com/camptocamp/terraform-provider-puppetdb/internal/puppetdb"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+)
+
+const defaultCertExpiryWarning = 30 * 24 * time.Hour
+
+// checkCertificates warns about the client and CA certificates expiring
+// within the given window, and fails if they already expired or if the
+// client certificate was not issued by the CA.
+func checkCertificates(tlsConfig *tls.Config, caPEM string, window time.Duration) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	caCerts := puppetdb.ParseCertificates(caPEM)
+
+	for _, caCert := range caCerts {
+		diags.Append(checkExpiry("CA certificate", caCert, window)...)
+	}
+
+	if len(tlsConfig.Certificates) == 0 {
+		return diags
+	}
+
+	cert, err := x509.ParseCertificate(tlsConfig.Certificates[0].Certificate[0])
+	if err != nil {
+		diags.AddError("Failed to parse client certificate", "Reason: "+err.Error())
+
+		return diags
+	}
+
+	diags.Append(checkExpiry("Client certificate", cert, window)...)
+
+	if len(caCerts) == 0 {
+		return diags
+	}
+
+	var intermediates []*x509.Certificate
+
+	for _, der := range tlsConfig.Certificates[0].Certificate[1:] {
+		if intermediate, err := x509.ParseCertificate(der); err == nil {
+			intermediates = append(intermediates, intermediate)
+		}
+	}
+
+	if !issuedBy(cert, intermediates, caCerts) {
+		diags.AddError("Client certificate was not issued by the configured CA",
+			fmt.Sprintf("Certificate %s is not signed by the configured CA, either directly or through the intermediates sent with it.", cert.Subject))
+	}
+
+	return diags
+}
+
+// issuedBy reports whether cert is signed by one of the CA certificates,
+// possibly through a chain of intermediates. Only signatures are checked,
+// not validity periods, so that certificates issued before a CA renewal
+// with the same key are still accepted.
+func issuedBy(cert *x509.Certificate, intermediates []*x509.Certificate, caCerts []*x509.Certificate) bool {
+	for depth := 0; depth <= len(intermediates); depth++ {
+		for _, caCert := range caCerts {
+			if cert.CheckSignatureFrom(caCert) == nil {
+				return true
+			}
+		}
+
+		var parent *x509.Certificate
+
+		for _, intermediate := range intermediates {
+			if cert.CheckSignatureFrom(intermediate) == nil {
+				parent = intermediate
+
+				break
+			}
+		}
+
+		if parent == nil {
+			return false
+		}
+
+		cert = parent
+	}
+
+	return false
+}
+
+func checkExpiry(name string, cert *x509.Certificate, window time.Duration) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	switch remaining := time.Until(cert.NotAfter); {
+	case remaining <= 0:
+		diags.AddError(name+" has expired",
+			fmt.Sprintf("%s %s expired on %s.", name, cert.Subject, cert.NotAfter.Format(time.RFC3339)))
+	case remaining < window:
+		diags.AddWarning(name+" expires soon",
+			fmt.Sprintf("%s %s expires on %s, in %s.", name, cert.Subject, cert.NotAfter.Format(time.RFC3339), remaining.Round(time.Hour)))
+	}
+
+	return diags
+}
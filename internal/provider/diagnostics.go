@@ -0,0 +1,38 @@
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/camptocamp/terraform-provider-puppetdb/internal/puppetdb"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+)
+
+// ErrorDiagnostic reports err under the given summary, unless it is an
+// error that deserves its own diagnostic whatever the failing operation.
+func ErrorDiagnostic(summary string, err error) diag.Diagnostic {
+	var revokedErr *puppetdb.CertificateRevokedError
+
+	if errors.As(err, &revokedErr) {
+		return diag.NewErrorDiagnostic("PuppetDB server certificate is revoked",
+			fmt.Sprintf("The certificate of %s (serial %s) was revoked on %s according to the configured CRL. Reason: %s",
+				revokedErr.Subject, revokedErr.SerialNumber, revokedErr.RevokedAt.Format(time.RFC3339), err.Error()))
+	}
+
+	var crlExpiredErr *puppetdb.CRLExpiredError
+
+	if errors.As(err, &crlExpiredErr) {
+		return diag.NewErrorDiagnostic("Puppet CA revocation list is expired",
+			fmt.Sprintf("The CRL of %s expired on %s, fetch a fresh one from the Puppet CA. Reason: %s",
+				crlExpiredErr.Issuer, crlExpiredErr.NextUpdate.Format(time.RFC3339), err.Error()))
+	}
+
+	var versionErr *puppetdb.UnsupportedVersionError
+
+	if errors.As(err, &versionErr) {
+		return diag.NewErrorDiagnostic(fmt.Sprintf("Requires PuppetDB >= %s", versionErr.MinVersion), versionErr.Error()+".")
+	}
+
+	return diag.NewErrorDiagnostic(summary, "Reason: "+err.Error())
+}
@@ -0,0 +1,103 @@
+package provider
+
+import (
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// pemSource holds the settings from which a PEM value can be loaded, in
+// order of precedence.
+type pemSource struct {
+	file      types.String
+	pem       types.String
+	pathOrPEM types.String
+	env       string
+
+	// fallback is a path, typically derived from the Puppet configuration.
+	fallback string
+}
+
+func (s pemSource) load() (string, error) {
+	switch {
+	case !s.file.IsNull():
+		return readPEMFile(s.file.ValueString())
+	case !s.pem.IsNull():
+		value, ok := decodePEM(s.pem.ValueString())
+		if !ok {
+			return "", fmt.Errorf("value is neither PEM nor base64-encoded PEM")
+		}
+
+		return value, nil
+	case !s.pathOrPEM.IsNull():
+		return detectPEM(s.pathOrPEM.ValueString())
+	}
+
+	if v := os.Getenv(s.env); v != "" {
+		return detectPEM(v)
+	}
+
+	if s.fallback != "" {
+		return readPEMFile(s.fallback)
+	}
+
+	return "", nil
+}
+
+// decodePEM returns value if it is PEM-encoded, or its decoded content if
+// it is base64-encoded PEM.
+func decodePEM(value string) (string, bool) {
+	if isPEM(value) {
+		return value, true
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(value))
+	if err != nil || !isPEM(string(decoded)) {
+		return "", false
+	}
+
+	return string(decoded), true
+}
+
+// detectPEM supports the settings which accept either a path or
+// the PEM content itself.
+func detectPEM(value string) (string, error) {
+	if decoded, ok := decodePEM(value); ok {
+		return decoded, nil
+	}
+
+	return readPEMFile(value)
+}
+
+func readPEMFile(path string) (string, error) {
+	content, err := readFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	decoded, ok := decodePEM(string(content))
+	if !ok {
+		return "", fmt.Errorf("%s does not contain PEM data", path)
+	}
+
+	return decoded, nil
+}
+
+func readFile(path string) ([]byte, error) {
+	path, err := expandHome(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return os.ReadFile(path)
+}
+
+func isPEM(value string) bool {
+	block, _ := pem.Decode([]byte(value))
+
+	return block != nil
+}
@@ -2,31 +2,98 @@ package provider
 
 import (
 	"context"
+	"crypto/tls"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/camptocamp/terraform-provider-puppetdb/internal/puppetconf"
 	"github.com/camptocamp/terraform-provider-puppetdb/internal/puppetdb"
+	"github.com/camptocamp/terraform-provider-puppetdb/internal/validators"
+	"github.com/hashicorp/terraform-plugin-framework-validators/int64validator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/providervalidator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
 	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/provider"
 	"github.com/hashicorp/terraform-plugin-framework/provider/schema"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
 	"github.com/hashicorp/terraform-plugin-framework/types"
 	"github.com/hashicorp/terraform-plugin-log/tflog"
 )
 
+var tlsVersions = map[string]uint16{
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+const DefaultBackend = "default"
+
 type Provider struct {
 	name        string
 	version     string
 	dataSources []func() datasource.DataSource
 	resources   []func() resource.Resource
 
-	client puppetdb.Client
+	client                         *puppetdb.Client
+	backends                       map[string]*puppetdb.Client
+	preventDestroyIfReportedWithin time.Duration
+	maxQueueDepth                  types.Int64
 }
 
 type Model struct {
 	Url           types.String `tfsdk:"url"`
+	URLs          types.List   `tfsdk:"urls"`
+	QueryURL      types.String `tfsdk:"query_url"`
+	CommandURL    types.String `tfsdk:"command_url"`
 	CACertificate types.String `tfsdk:"ca"`
 	Certificate   types.String `tfsdk:"cert"`
 	PrivateKey    types.String `tfsdk:"key"`
+	CAFile        types.String `tfsdk:"ca_file"`
+	CAPEM         types.String `tfsdk:"ca_pem"`
+	CertFile      types.String `tfsdk:"cert_file"`
+	CertPEM       types.String `tfsdk:"cert_pem"`
+	KeyFile       types.String `tfsdk:"key_file"`
+	KeyPEM        types.String `tfsdk:"key_pem"`
+	PKCS12File    types.String `tfsdk:"pkcs12_file"`
+	KeyPassword   types.String `tfsdk:"key_password"`
+
+	TLSServerName      types.String `tfsdk:"tls_server_name"`
+	TLSMinVersion      types.String `tfsdk:"tls_min_version"`
+	InsecureSkipVerify types.Bool   `tfsdk:"insecure_skip_verify"`
+	CRL                types.String `tfsdk:"crl"`
+	CertExpiryWarning  types.String `tfsdk:"cert_expiry_warning"`
+
+	ProxyURL  types.String    `tfsdk:"proxy_url"`
+	NoProxy   types.String    `tfsdk:"no_proxy"`
+	BasicAuth *BasicAuthModel `tfsdk:"basic_auth"`
+	Headers   types.Map       `tfsdk:"headers"`
+
+	Backends  map[string]BackendModel `tfsdk:"backends"`
+	Token     types.String            `tfsdk:"token"`
+	TokenFile types.String            `tfsdk:"token_file"`
+
+	UsePuppetConfig types.Bool `tfsdk:"use_puppet_config"`
+
+	ReadOnly     types.Bool   `tfsdk:"read_only"`
+	AuditLogPath types.String `tfsdk:"audit_log_path"`
+
+	PreventDestroyIfReportedWithin types.String `tfsdk:"prevent_destroy_if_reported_within"`
+	MaxQueueDepth                  types.Int64  `tfsdk:"max_queue_depth"`
+}
+
+type BackendModel struct {
+	Url        types.String `tfsdk:"url"`
+	URLs       types.List   `tfsdk:"urls"`
+	QueryURL   types.String `tfsdk:"query_url"`
+	CommandURL types.String `tfsdk:"command_url"`
+}
+
+type BasicAuthModel struct {
+	Username types.String `tfsdk:"username"`
+	Password types.String `tfsdk:"password"`
 }
 
 func (p *Provider) Metadata(ctx context.Context, req provider.MetadataRequest, resp *provider.MetadataResponse) {
@@ -41,23 +108,199 @@ func (p *Provider) Schema(ctx context.Context, req provider.SchemaRequest, resp
 				Optional:    true,
 				Description: "URL of the PuppetDB",
 			},
-			"ca": schema.StringAttribute{
+			"urls": schema.ListAttribute{
+				ElementType: types.StringType,
 				Optional:    true,
-				Description: "Puppet CA certificate",
+				Description: "URLs of the PuppetDB servers, tried in order when one fails",
 			},
-			"cert": schema.StringAttribute{
+			"query_url": schema.StringAttribute{
 				Optional:    true,
-				Description: "Certificate to authenticate against PuppetDB",
+				Description: "URL of the PuppetDB to send queries to, such as a read replica, defaults to url",
+			},
+			"command_url": schema.StringAttribute{
+				Optional:    true,
+				Description: "URL of the PuppetDB to submit commands to, such as the primary, defaults to url",
+			},
+			"ca": schema.StringAttribute{
+				Optional:           true,
+				Description:        "Puppet CA certificate, as a path or PEM",
+				DeprecationMessage: "Use ca_file or ca_pem instead",
+			},
+			"cert": schema.StringAttribute{
+				Optional:           true,
+				Description:        "Certificate to authenticate against PuppetDB, as a path or PEM",
+				DeprecationMessage: "Use cert_file or cert_pem instead",
 			},
 			"key": schema.StringAttribute{
+				Sensitive:          true,
+				Optional:           true,
+				Description:        "Private key to authenticate against PuppetDB, as a path or PEM",
+				DeprecationMessage: "Use key_file or key_pem instead",
+			},
+			"ca_file": schema.StringAttribute{
+				Optional:    true,
+				Description: "Path to the Puppet CA certificate",
+			},
+			"ca_pem": schema.StringAttribute{
+				Optional:    true,
+				Description: "Puppet CA certificate, PEM or base64-encoded PEM",
+			},
+			"cert_file": schema.StringAttribute{
+				Optional:    true,
+				Description: "Path to the certificate to authenticate against PuppetDB",
+			},
+			"cert_pem": schema.StringAttribute{
+				Optional:    true,
+				Description: "Certificate to authenticate against PuppetDB, PEM or base64-encoded PEM",
+			},
+			"key_file": schema.StringAttribute{
+				Optional:    true,
+				Description: "Path to the private key to authenticate against PuppetDB",
+			},
+			"key_pem": schema.StringAttribute{
+				Sensitive:   true,
+				Optional:    true,
+				Description: "Private key to authenticate against PuppetDB, PEM or base64-encoded PEM",
+			},
+			"pkcs12_file": schema.StringAttribute{
+				Optional:    true,
+				Description: "Path to a PKCS#12 bundle holding the certificate and private key to authenticate against PuppetDB",
+			},
+			"key_password": schema.StringAttribute{
 				Sensitive:   true,
 				Optional:    true,
-				Description: "Private key to authenticate against PuppetDB",
+				Description: "Password of the encrypted private key or PKCS#12 bundle",
+			},
+			"tls_server_name": schema.StringAttribute{
+				Optional:    true,
+				Description: "Server name to verify the PuppetDB certificate against, instead of the URL host",
+			},
+			"tls_min_version": schema.StringAttribute{
+				Optional:    true,
+				Description: "Minimum TLS version, either 1.2 (default) or 1.3",
+				Validators: []validator.String{
+					stringvalidator.OneOf("1.2", "1.3"),
+				},
+			},
+			"insecure_skip_verify": schema.BoolAttribute{
+				Optional:    true,
+				Description: "Skip the verification of the PuppetDB certificate, for lab use only",
+			},
+			"crl": schema.StringAttribute{
+				Optional:    true,
+				Description: "Puppet CA certificate revocation list, as a path or PEM, to check the PuppetDB certificate against",
+			},
+			"cert_expiry_warning": schema.StringAttribute{
+				Optional:    true,
+				Description: "Warn when the client or CA certificate expires within this duration, defaults to 720h",
+				Validators: []validator.String{
+					validators.Duration(),
+				},
+			},
+			"proxy_url": schema.StringAttribute{
+				Optional:    true,
+				Description: "URL of the HTTP proxy to connect to PuppetDB through, defaults to the HTTPS_PROXY and NO_PROXY environment variables",
+			},
+			"no_proxy": schema.StringAttribute{
+				Optional:    true,
+				Description: "Comma-separated list of hosts which are not reached through the proxy",
+			},
+			"basic_auth": schema.SingleNestedAttribute{
+				Optional:    true,
+				Description: "Credentials for a reverse proxy in front of PuppetDB requiring HTTP basic authentication",
+				Attributes: map[string]schema.Attribute{
+					"username": schema.StringAttribute{
+						Required: true,
+					},
+					"password": schema.StringAttribute{
+						Sensitive: true,
+						Required:  true,
+					},
+				},
+			},
+			"headers": schema.MapAttribute{
+				ElementType: types.StringType,
+				Optional:    true,
+				Description: "Additional HTTP headers sent with every request",
+			},
+			"backends": schema.MapNestedAttribute{
+				Optional:    true,
+				Description: "Named PuppetDB instances which data sources can query together with the provider's own connection, known as the default backend, sharing its TLS and authentication settings",
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"url": schema.StringAttribute{
+							Optional:    true,
+							Description: "URL of the PuppetDB",
+							Validators: []validator.String{
+								stringvalidator.ConflictsWith(path.MatchRelative().AtParent().AtName("urls")),
+							},
+						},
+						"urls": schema.ListAttribute{
+							ElementType: types.StringType,
+							Optional:    true,
+							Description: "URLs of the PuppetDB servers, tried in order when one fails",
+						},
+						"query_url": schema.StringAttribute{
+							Optional:    true,
+							Description: "URL of the PuppetDB to send queries to, defaults to url",
+						},
+						"command_url": schema.StringAttribute{
+							Optional:    true,
+							Description: "URL of the PuppetDB to submit commands to, defaults to url",
+						},
+					},
+				},
+			},
+			"token": schema.StringAttribute{
+				Sensitive:   true,
+				Optional:    true,
+				Description: "Puppet Enterprise RBAC token to authenticate against PuppetDB",
+			},
+			"token_file": schema.StringAttribute{
+				Optional:    true,
+				Description: "File containing a Puppet Enterprise RBAC token, defaults to ~/.puppetlabs/token",
+			},
+			"use_puppet_config": schema.BoolAttribute{
+				Optional:    true,
+				Description: "Derive the URL, CA, certificate and key from the local puppet.conf and puppetdb.conf",
+			},
+			"read_only": schema.BoolAttribute{
+				Optional:    true,
+				Description: "Refuse to submit any command to PuppetDB",
+			},
+			"audit_log_path": schema.StringAttribute{
+				Optional:    true,
+				Description: "File to which a JSON line is appended for each command submitted to PuppetDB",
+			},
+			"prevent_destroy_if_reported_within": schema.StringAttribute{
+				Optional:    true,
+				Description: "Default duration during which nodes that sent a report cannot be deactivated",
+				Validators: []validator.String{
+					validators.Duration(),
+				},
+			},
+			"max_queue_depth": schema.Int64Attribute{
+				Optional:    true,
+				Description: "Wait until the PuppetDB command queue holds at most this many commands before reading node state",
+				Validators: []validator.Int64{
+					int64validator.AtLeast(0),
+				},
 			},
 		},
 	}
 }
 
+func (p *Provider) ConfigValidators(ctx context.Context) []provider.ConfigValidator {
+	return []provider.ConfigValidator{
+		providervalidator.Conflicting(path.MatchRoot("url"), path.MatchRoot("urls")),
+		providervalidator.Conflicting(path.MatchRoot("ca"), path.MatchRoot("ca_file"), path.MatchRoot("ca_pem")),
+		providervalidator.Conflicting(path.MatchRoot("cert"), path.MatchRoot("cert_file"), path.MatchRoot("cert_pem")),
+		providervalidator.Conflicting(path.MatchRoot("key"), path.MatchRoot("key_file"), path.MatchRoot("key_pem")),
+		providervalidator.Conflicting(path.MatchRoot("pkcs12_file"), path.MatchRoot("cert"), path.MatchRoot("cert_file"), path.MatchRoot("cert_pem")),
+		providervalidator.Conflicting(path.MatchRoot("pkcs12_file"), path.MatchRoot("key"), path.MatchRoot("key_file"), path.MatchRoot("key_pem")),
+	}
+}
+
 func (p *Provider) Configure(ctx context.Context, req provider.ConfigureRequest, resp *provider.ConfigureResponse) {
 	var config Model
 
@@ -67,50 +310,282 @@ func (p *Provider) Configure(ctx context.Context, req provider.ConfigureRequest,
 		return
 	}
 
-	url := os.Getenv("PUPPETDB_URL")
-	cacert := os.Getenv("PUPPETDB_CA")
-	cert := os.Getenv("PUPPETDB_CERT")
-	key := os.Getenv("PUPPETDB_KEY")
+	puppetConfig := &puppetconf.Config{}
+
+	if config.UsePuppetConfig.ValueBool() {
+		var err error
+
+		puppetConfig, err = puppetconf.Load()
+
+		if err != nil {
+			resp.Diagnostics.AddError("Failed to load Puppet configuration", "Reason: "+err.Error())
+			return
+		}
+
+		tflog.Debug(ctx, "Loaded Puppet configuration", map[string]any{
+			"certname": puppetConfig.Certname,
+			"urls":     puppetConfig.URLs,
+		})
+	}
+
+	urls := puppetConfig.URLs
+	readOnly := false
+
+	if v := os.Getenv("PUPPETDB_URL"); v != "" {
+		urls = puppetconf.SplitURLs(v)
+	}
+
+	if v := os.Getenv("PUPPETDB_READ_ONLY"); v != "" {
+		var err error
+
+		readOnly, err = strconv.ParseBool(v)
+
+		if err != nil {
+			resp.Diagnostics.AddError("Invalid PUPPETDB_READ_ONLY environment variable", "Reason: "+err.Error())
+			return
+		}
+	}
 
 	if !config.Url.IsNull() {
-		url = config.Url.ValueString()
+		urls = []string{config.Url.ValueString()}
+	}
+
+	if !config.URLs.IsNull() {
+		resp.Diagnostics.Append(config.URLs.ElementsAs(ctx, &urls, false)...)
+
+		if resp.Diagnostics.HasError() {
+			return
+		}
 	}
 
-	if !config.CACertificate.IsNull() {
-		cacert = config.CACertificate.ValueString()
+	if !config.ReadOnly.IsNull() {
+		readOnly = config.ReadOnly.ValueBool()
 	}
 
-	if !config.Certificate.IsNull() {
-		cert = config.Certificate.ValueString()
+	if len(urls) == 0 {
+		urls = []string{"https://puppet:8140"}
 	}
 
-	if !config.PrivateKey.IsNull() {
-		key = config.PrivateKey.ValueString()
+	queryURL := getenv("PUPPETDB_QUERY_URL", "")
+	commandURL := getenv("PUPPETDB_COMMAND_URL", "")
+
+	if !config.QueryURL.IsNull() {
+		queryURL = config.QueryURL.ValueString()
 	}
 
-	if url == "" {
-		url = "https://puppet:8140"
+	if !config.CommandURL.IsNull() {
+		commandURL = config.CommandURL.ValueString()
 	}
 
-	var err error
+	cacert, err := pemSource{config.CAFile, config.CAPEM, config.CACertificate, "PUPPETDB_CA", puppetConfig.CA}.load()
 
-	p.client = puppetdb.Client{
-		URL:  url,
-		CA:   cacert,
-		Cert: cert,
-		Key:  key,
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to load CA certificate", "Reason: "+err.Error())
+		return
 	}
 
+	cert, err := pemSource{config.CertFile, config.CertPEM, config.Certificate, "PUPPETDB_CERT", puppetConfig.Cert}.load()
+
 	if err != nil {
-		resp.Diagnostics.AddError("Failed to create PuppetDB client", "Reason: "+err.Error())
+		resp.Diagnostics.AddError("Failed to load client certificate", "Reason: "+err.Error())
+		return
+	}
+
+	key, err := pemSource{config.KeyFile, config.KeyPEM, config.PrivateKey, "PUPPETDB_KEY", puppetConfig.Key}.load()
+
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to load private key", "Reason: "+err.Error())
+		return
+	}
+
+	if (cert == "") != (key == "") {
+		resp.Diagnostics.AddError("Invalid client certificate configuration", "A client certificate and a private key must be set together.")
+		return
+	}
+
+	var pkcs12 []byte
+
+	if !config.PKCS12File.IsNull() {
+		pkcs12, err = readFile(config.PKCS12File.ValueString())
+
+		if err != nil {
+			resp.Diagnostics.AddError("Failed to load PKCS#12 bundle", "Reason: "+err.Error())
+			return
+		}
+
+		if cert != "" {
+			resp.Diagnostics.AddError("Invalid client certificate configuration", "A PKCS#12 bundle cannot be used along with a client certificate and private key.")
+			return
+		}
+	}
+
+	keyPassword := getenv("PUPPETDB_KEY_PASSWORD", "")
+
+	if !config.KeyPassword.IsNull() {
+		keyPassword = config.KeyPassword.ValueString()
+	}
+
+	token, err := resolveToken(config.Token, config.TokenFile)
+
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to load PE RBAC token", "Reason: "+err.Error())
+		return
+	}
+
+	if !config.PreventDestroyIfReportedWithin.IsNull() {
+		duration, err := time.ParseDuration(config.PreventDestroyIfReportedWithin.ValueString())
+
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("prevent_destroy_if_reported_within"), "Invalid duration", "Reason: "+err.Error())
+			return
+		}
+
+		p.preventDestroyIfReportedWithin = duration
+	}
+
+	p.maxQueueDepth = config.MaxQueueDepth
+
+	crl, err := pemSource{pathOrPEM: config.CRL}.load()
+
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("crl"), "Failed to load CRL", "Reason: "+err.Error())
+		return
+	}
+
+	var headers map[string]string
+
+	resp.Diagnostics.Append(config.Headers.ElementsAs(ctx, &headers, false)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var basicAuth BasicAuthModel
+
+	if config.BasicAuth != nil {
+		basicAuth = *config.BasicAuth
+	}
+
+	var tlsMinVersion uint16
+
+	if !config.TLSMinVersion.IsNull() {
+		tlsMinVersion = tlsVersions[config.TLSMinVersion.ValueString()]
+	}
+
+	if config.InsecureSkipVerify.ValueBool() {
+		resp.Diagnostics.AddAttributeWarning(path.Root("insecure_skip_verify"), "TLS certificate verification is disabled",
+			"The PuppetDB server certificate will not be verified, so any server can impersonate PuppetDB and intercept credentials. Only use this in lab environments.")
+	}
+
+	newClient := func(urls []string, queryURL string, commandURL string) *puppetdb.Client {
+		trimmedURLs := make([]string, len(urls))
+
+		for i, url := range urls {
+			trimmedURLs[i] = strings.TrimSuffix(url, "/")
+		}
+
+		return &puppetdb.Client{
+			URL:         trimmedURLs[0],
+			URLs:        trimmedURLs,
+			QueryURL:    strings.TrimSuffix(queryURL, "/"),
+			CommandURL:  strings.TrimSuffix(commandURL, "/"),
+			CA:          cacert,
+			Cert:        cert,
+			Key:         key,
+			PKCS12:      pkcs12,
+			KeyPassword: keyPassword,
+			Token:       token,
+			ReadOnly:    readOnly,
+
+			AuditLogPath: config.AuditLogPath.ValueString(),
+
+			CRL:                   crl,
+			TLSServerName:         config.TLSServerName.ValueString(),
+			TLSMinVersion:         tlsMinVersion,
+			TLSInsecureSkipVerify: config.InsecureSkipVerify.ValueBool(),
+
+			ProxyURL:          config.ProxyURL.ValueString(),
+			NoProxy:           config.NoProxy.ValueString(),
+			BasicAuthUsername: basicAuth.Username.ValueString(),
+			BasicAuthPassword: basicAuth.Password.ValueString(),
+			Headers:           headers,
+		}
+	}
+
+	p.client = newClient(urls, queryURL, commandURL)
+	p.backends = map[string]*puppetdb.Client{
+		DefaultBackend: p.client,
+	}
+
+	for name, backend := range config.Backends {
+		if name == DefaultBackend {
+			resp.Diagnostics.AddAttributeError(path.Root("backends").AtMapKey(name), "Invalid backend", "The name "+DefaultBackend+" is reserved for the provider's own connection.")
+			return
+		}
+
+		var backendURLs []string
+
+		if !backend.Url.IsNull() {
+			backendURLs = []string{backend.Url.ValueString()}
+		}
+
+		if !backend.URLs.IsNull() {
+			resp.Diagnostics.Append(backend.URLs.ElementsAs(ctx, &backendURLs, false)...)
+		}
+
+		if len(backendURLs) == 0 {
+			resp.Diagnostics.AddAttributeError(path.Root("backends").AtMapKey(name), "Invalid backend", "Either url or urls must be set.")
+		}
+
+		if resp.Diagnostics.HasError() {
+			return
+		}
+
+		p.backends[name] = newClient(backendURLs, backend.QueryURL.ValueString(), backend.CommandURL.ValueString())
+	}
+
+	tlsConfig, err := p.client.TLSConfig()
+
+	if err != nil {
+		resp.Diagnostics.Append(ErrorDiagnostic("Failed to create PuppetDB client", err))
+		return
+	}
+
+	certExpiryWarning := defaultCertExpiryWarning
+
+	if !config.CertExpiryWarning.IsNull() {
+		certExpiryWarning, err = time.ParseDuration(config.CertExpiryWarning.ValueString())
+
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("cert_expiry_warning"), "Invalid duration", "Reason: "+err.Error())
+			return
+		}
+	}
+
+	resp.Diagnostics.Append(checkCertificates(tlsConfig, cacert, certExpiryWarning)...)
+
+	if resp.Diagnostics.HasError() {
 		return
 	}
 
 	tflog.Info(ctx, "Successfully created PuppetDB client", map[string]any{
-		"url": url,
+		"urls":        urls,
+		"query_url":   queryURL,
+		"command_url": commandURL,
+		"read_only":   readOnly,
 	})
 }
 
+// getenv returns the value of the environment variable, or fallback when it
+// is not set.
+func getenv(name string, fallback string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+
+	return fallback
+}
+
 func (p *Provider) DataSources(ctx context.Context) []func() datasource.DataSource {
 	return p.dataSources
 }
@@ -120,7 +595,23 @@ func (p *Provider) Resources(ctx context.Context) []func() resource.Resource {
 }
 
 func (p *Provider) Client() *puppetdb.Client {
-	return &p.client
+	return p.client
+}
+
+// Backends returns the named PuppetDB clients which data sources can fan
+// out to, including the provider's own client as the default one.
+func (p *Provider) Backends() map[string]*puppetdb.Client {
+	return p.backends
+}
+
+func (p *Provider) PreventDestroyIfReportedWithin() time.Duration {
+	return p.preventDestroyIfReportedWithin
+}
+
+// MaxQueueDepth returns the command queue depth above which node state is
+// not read yet, or null to read it right away.
+func (p *Provider) MaxQueueDepth() types.Int64 {
+	return p.maxQueueDepth
 }
 
 func NewFactory(name string, version string, ds []func(p *Provider) datasource.DataSource, rs []func(p *Provider) resource.Resource) func() provider.Provider {
@@ -151,6 +642,7 @@ func NewFactory(name string, version string, ds []func(p *Provider) datasource.D
 		}
 
 		var _ provider.Provider = p
+		var _ provider.ProviderWithConfigValidators = p
 
 		return p
 	}
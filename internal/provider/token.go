@@ -0,0 +1,64 @@
+package provider
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+const defaultTokenFile = "~/.puppetlabs/token"
+
+// resolveToken returns the PE RBAC token to authenticate with, looking in
+// order at the token and token_file settings, the PE_TOKEN environment
+// variable and the default token file written by `puppet access login`.
+func resolveToken(token types.String, tokenFile types.String) (string, error) {
+	if !token.IsNull() {
+		return token.ValueString(), nil
+	}
+
+	if !tokenFile.IsNull() {
+		return readToken(tokenFile.ValueString())
+	}
+
+	if v := os.Getenv("PE_TOKEN"); v != "" {
+		return v, nil
+	}
+
+	t, err := readToken(defaultTokenFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+
+	return t, err
+}
+
+func readToken(path string) (string, error) {
+	path, err := expandHome(path)
+	if err != nil {
+		return "", err
+	}
+
+	t, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(t)), nil
+}
+
+// expandHome replaces a leading "~" with the current user's home directory.
+func expandHome(path string) (string, error) {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path, nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(home, path[1:]), nil
+}
@@ -0,0 +1,70 @@
+package puppetconf
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+var (
+	sectionRegexp = regexp.MustCompile(`^\[([^\]]+)\]$`)
+	settingRegexp = regexp.MustCompile(`^([\w.-]+)\s*=\s*(.*?)$`)
+
+	// Puppet allows file settings to be followed by metadata such as
+	// `{ owner = service, mode = 0644 }`, which is irrelevant here.
+	metadataRegexp = regexp.MustCompile(`\s*\{[^}]*\}\s*$`)
+)
+
+type sections map[string]map[string]string
+
+// parse reads an ini file as used by puppet.conf and puppetdb.conf.
+// Settings found before any section header belong to the main section.
+func parse(r io.Reader) (sections, error) {
+	s := sections{}
+	section := "main"
+	lineNumber := 0
+
+	scanner := bufio.NewScanner(r)
+
+	for scanner.Scan() {
+		lineNumber++
+
+		line := strings.TrimSpace(scanner.Text())
+
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+
+		if m := sectionRegexp.FindStringSubmatch(line); m != nil {
+			section = strings.TrimSpace(m[1])
+
+			continue
+		}
+
+		m := settingRegexp.FindStringSubmatch(line)
+		if m == nil {
+			return nil, fmt.Errorf("line %d: invalid setting %q", lineNumber, line)
+		}
+
+		if s[section] == nil {
+			s[section] = map[string]string{}
+		}
+
+		s[section][m[1]] = metadataRegexp.ReplaceAllString(m[2], "")
+	}
+
+	return s, scanner.Err()
+}
+
+// get returns the first value set for the setting in the given sections.
+func (s sections) get(name string, sectionNames ...string) (string, bool) {
+	for _, sectionName := range sectionNames {
+		if value, ok := s[sectionName][name]; ok {
+			return value, true
+		}
+	}
+
+	return "", false
+}
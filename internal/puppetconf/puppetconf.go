@@ -0,0 +1,261 @@
+// Package puppetconf derives PuppetDB connection settings from a local
+// Puppet agent configuration, i.e. puppet.conf and puppetdb.conf.
+package puppetconf
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const (
+	systemConfdir = "/etc/puppetlabs/puppet"
+	userConfdir   = ".puppetlabs/etc/puppet"
+	resolvConf    = "/etc/resolv.conf"
+
+	maxInterpolationDepth = 10
+)
+
+var (
+	variableRegexp = regexp.MustCompile(`\$(\w+)|\$\{(\w+)\}`)
+
+	// Sections of puppet.conf looked at, in order of precedence.
+	puppetSections = []string{"agent", "main"}
+)
+
+type Config struct {
+	Certname string
+	URLs     []string
+	CA       string
+	Cert     string
+	Key      string
+}
+
+// Load reads the Puppet configuration from the user's confdir when it
+// exists and the user is not root, or from the system confdir otherwise.
+func Load() (*Config, error) {
+	confdir := systemConfdir
+
+	if os.Geteuid() != 0 {
+		if home, err := os.UserHomeDir(); err == nil {
+			if _, err := os.Stat(filepath.Join(home, userConfdir, "puppet.conf")); err == nil {
+				confdir = filepath.Join(home, userConfdir)
+			}
+		}
+	}
+
+	return LoadFrom(confdir)
+}
+
+// LoadFrom reads puppet.conf and puppetdb.conf from the given confdir.
+func LoadFrom(confdir string) (*Config, error) {
+	puppetConf, err := parseFile(filepath.Join(confdir, "puppet.conf"))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	puppetDBConf, err := parseFile(filepath.Join(confdir, "puppetdb.conf"))
+	if err != nil {
+		return nil, err
+	}
+
+	r := resolver{
+		sections: puppetConf,
+		defaults: defaults(confdir),
+	}
+
+	// Only look up the FQDN when needed, as it may query DNS.
+	if _, ok := puppetConf.get("certname", puppetSections...); !ok {
+		certname, err := fqdn()
+		if err != nil {
+			return nil, err
+		}
+
+		r.defaults["certname"] = strings.ToLower(certname)
+	}
+
+	config := &Config{}
+
+	for setting, value := range map[string]*string{
+		"certname":    &config.Certname,
+		"localcacert": &config.CA,
+		"hostcert":    &config.Cert,
+		"hostprivkey": &config.Key,
+	} {
+		if *value, err = r.resolve(setting); err != nil {
+			return nil, err
+		}
+	}
+
+	serverURLs, ok := puppetDBConf.get("server_urls", "main")
+	if !ok {
+		return nil, fmt.Errorf("server_urls is not set in %s", filepath.Join(confdir, "puppetdb.conf"))
+	}
+
+	config.URLs = SplitURLs(serverURLs)
+
+	return config, nil
+}
+
+// defaults returns the default Puppet settings for the confdir. Other
+// directories live under /opt/puppetlabs and /var for the system confdir,
+// and next to the confdir, as in ~/.puppetlabs, otherwise.
+func defaults(confdir string) map[string]string {
+	d := map[string]string{
+		"confdir":       confdir,
+		"ssldir":        "$confdir/ssl",
+		"certdir":       "$ssldir/certs",
+		"privatekeydir": "$ssldir/private_keys",
+		"localcacert":   "$certdir/ca.pem",
+		"hostcert":      "$certdir/$certname.pem",
+		"hostprivkey":   "$privatekeydir/$certname.pem",
+	}
+
+	if filepath.Clean(confdir) == systemConfdir {
+		d["codedir"] = "/etc/puppetlabs/code"
+		d["vardir"] = "/opt/puppetlabs/puppet/cache"
+		d["rundir"] = "/var/run/puppetlabs"
+		d["logdir"] = "/var/log/puppetlabs/puppet"
+	} else {
+		base := filepath.Dir(filepath.Dir(filepath.Clean(confdir)))
+
+		d["codedir"] = filepath.Join(base, "etc", "code")
+		d["vardir"] = filepath.Join(base, "opt", "puppet", "cache")
+		d["rundir"] = filepath.Join(base, "var", "run")
+		d["logdir"] = filepath.Join(base, "var", "log")
+	}
+
+	return d
+}
+
+// fqdn returns the fully qualified domain name of the host, which Puppet
+// uses as the default certname, falling back to the domain of resolv.conf
+// when the hostname is short and does not resolve to a longer name.
+func fqdn() (string, error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return "", err
+	}
+
+	if strings.Contains(hostname, ".") {
+		return hostname, nil
+	}
+
+	if cname, err := net.LookupCNAME(hostname); err == nil {
+		if cname = strings.TrimSuffix(cname, "."); strings.Contains(cname, ".") {
+			return cname, nil
+		}
+	}
+
+	if domain := resolvDomain(resolvConf); domain != "" {
+		return hostname + "." + domain, nil
+	}
+
+	return hostname, nil
+}
+
+// resolvDomain returns the domain, or else the first search domain, set in
+// a resolv.conf file.
+func resolvDomain(path string) string {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+
+	var search string
+
+	for _, line := range strings.Split(string(content), "\n") {
+		fields := strings.Fields(line)
+
+		if len(fields) < 2 {
+			continue
+		}
+
+		switch fields[0] {
+		case "domain":
+			return fields[1]
+		case "search":
+			if search == "" {
+				search = fields[1]
+			}
+		}
+	}
+
+	return search
+}
+
+// SplitURLs parses a comma-separated list of URLs such as server_urls.
+func SplitURLs(value string) []string {
+	var urls []string
+
+	for _, url := range strings.Split(value, ",") {
+		if url = strings.TrimSpace(url); url != "" {
+			urls = append(urls, strings.TrimSuffix(url, "/"))
+		}
+	}
+
+	return urls
+}
+
+func parseFile(path string) (sections, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	s, err := parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	return s, nil
+}
+
+type resolver struct {
+	sections sections
+	defaults map[string]string
+}
+
+// resolve returns the value of a Puppet setting, with variables such as
+// $ssldir or ${certname} interpolated.
+func (r *resolver) resolve(name string) (string, error) {
+	return r.resolveDepth(name, 0)
+}
+
+func (r *resolver) resolveDepth(name string, depth int) (string, error) {
+	if depth > maxInterpolationDepth {
+		return "", fmt.Errorf("too many levels of interpolation while resolving %s", name)
+	}
+
+	value, ok := r.sections.get(name, puppetSections...)
+	if !ok {
+		if value, ok = r.defaults[name]; !ok {
+			return "", fmt.Errorf("unknown Puppet setting %s", name)
+		}
+	}
+
+	var err error
+
+	value = variableRegexp.ReplaceAllStringFunc(value, func(variable string) string {
+		m := variableRegexp.FindStringSubmatch(variable)
+
+		variableName := m[1]
+		if variableName == "" {
+			variableName = m[2]
+		}
+
+		resolved, resolveErr := r.resolveDepth(variableName, depth+1)
+		if resolveErr != nil && err == nil {
+			err = resolveErr
+		}
+
+		return resolved
+	})
+
+	return value, err
+}
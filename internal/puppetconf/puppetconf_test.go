@@ -0,0 +1,168 @@
+package puppetconf
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoadFrom(t *testing.T) {
+	tests := []struct {
+		name         string
+		puppetConf   string
+		puppetDBConf string
+		want         func(confdir string, base string) *Config
+		wantErr      bool
+	}{
+		{
+			name:         "defaults",
+			puppetConf:   "certname = foo.example.com\n",
+			puppetDBConf: "[main]\nserver_urls = https://puppetdb:8081\n",
+			want: func(confdir string, base string) *Config {
+				return &Config{
+					Certname: "foo.example.com",
+					URLs:     []string{"https://puppetdb:8081"},
+					CA:       confdir + "/ssl/certs/ca.pem",
+					Cert:     confdir + "/ssl/certs/foo.example.com.pem",
+					Key:      confdir + "/ssl/private_keys/foo.example.com.pem",
+				}
+			},
+		},
+		{
+			name:         "agent section takes precedence over main",
+			puppetConf:   "[main]\ncertname = main.example.com\nssldir = /main/ssl\n[agent]\ncertname = agent.example.com\n",
+			puppetDBConf: "[main]\nserver_urls = https://puppetdb:8081\n",
+			want: func(confdir string, base string) *Config {
+				return &Config{
+					Certname: "agent.example.com",
+					URLs:     []string{"https://puppetdb:8081"},
+					CA:       "/main/ssl/certs/ca.pem",
+					Cert:     "/main/ssl/certs/agent.example.com.pem",
+					Key:      "/main/ssl/private_keys/agent.example.com.pem",
+				}
+			},
+		},
+		{
+			name:         "default vardir",
+			puppetConf:   "certname = foo\nssldir = $vardir/ssl\n",
+			puppetDBConf: "[main]\nserver_urls = https://puppetdb:8081\n",
+			want: func(confdir string, base string) *Config {
+				return &Config{
+					Certname: "foo",
+					URLs:     []string{"https://puppetdb:8081"},
+					CA:       base + "/opt/puppet/cache/ssl/certs/ca.pem",
+					Cert:     base + "/opt/puppet/cache/ssl/certs/foo.pem",
+					Key:      base + "/opt/puppet/cache/ssl/private_keys/foo.pem",
+				}
+			},
+		},
+		{
+			name:         "braced variables and metadata",
+			puppetConf:   "[main]\ncertname = foo\nvardir = /var/puppet\nssldir = ${vardir}/ssl\nhostcert = ${certdir}/cert.pem { mode = 0644 }\n",
+			puppetDBConf: "[main]\nserver_urls = https://pdb1:8081/, https://pdb2:8081\n",
+			want: func(confdir string, base string) *Config {
+				return &Config{
+					Certname: "foo",
+					URLs:     []string{"https://pdb1:8081", "https://pdb2:8081"},
+					CA:       "/var/puppet/ssl/certs/ca.pem",
+					Cert:     "/var/puppet/ssl/certs/cert.pem",
+					Key:      "/var/puppet/ssl/private_keys/foo.pem",
+				}
+			},
+		},
+		{
+			name:         "unknown setting",
+			puppetConf:   "certname = foo\nssldir = $unknown/ssl\n",
+			puppetDBConf: "[main]\nserver_urls = https://puppetdb:8081\n",
+			wantErr:      true,
+		},
+		{
+			name:         "interpolation loop",
+			puppetConf:   "certname = foo\nssldir = $certdir\ncertdir = $ssldir\n",
+			puppetDBConf: "[main]\nserver_urls = https://puppetdb:8081\n",
+			wantErr:      true,
+		},
+		{
+			name:         "missing server_urls",
+			puppetConf:   "certname = foo\n",
+			puppetDBConf: "[main]\n",
+			wantErr:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			base := t.TempDir()
+			confdir := filepath.Join(base, "etc", "puppet")
+
+			if err := os.MkdirAll(confdir, 0o755); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := os.WriteFile(filepath.Join(confdir, "puppet.conf"), []byte(tt.puppetConf), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := os.WriteFile(filepath.Join(confdir, "puppetdb.conf"), []byte(tt.puppetDBConf), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := LoadFrom(confdir)
+
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("LoadFrom() = %+v, want an error", got)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("LoadFrom() failed: %v", err)
+			}
+
+			if want := tt.want(confdir, base); !reflect.DeepEqual(got, want) {
+				t.Errorf("LoadFrom() = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestResolvDomain(t *testing.T) {
+	tests := []struct {
+		name       string
+		resolvConf string
+		want       string
+	}{
+		{
+			name:       "domain",
+			resolvConf: "search other.example.com\ndomain example.com\nnameserver 10.0.0.1\n",
+			want:       "example.com",
+		},
+		{
+			name:       "first search domain",
+			resolvConf: "search example.com other.example.com\n",
+			want:       "example.com",
+		},
+		{
+			name:       "none",
+			resolvConf: "nameserver 10.0.0.1\n",
+			want:       "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "resolv.conf")
+
+			if err := os.WriteFile(path, []byte(tt.resolvConf), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := resolvDomain(path); got != tt.want {
+				t.Errorf("resolvDomain() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package puppetdb
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+)
+
+type AuditRecord struct {
+	Time              time.Time  `json:"time"`
+	Command           string     `json:"command"`
+	Version           int        `json:"version"`
+	Certname          string     `json:"certname"`
+	ProducerTimestamp *time.Time `json:"producer_timestamp,omitempty"`
+	Status            int        `json:"status"`
+	UUID              string     `json:"uuid,omitempty"`
+	Error             string     `json:"error,omitempty"`
+}
+
+func (p *Client) audit(record *AuditRecord) error {
+	if p.AuditLogPath == "" {
+		return nil
+	}
+
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(p.AuditLogPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+
+	_, err = f.Write(append(line, '\n'))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+func errorString(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	return err.Error()
+}
@@ -2,26 +2,95 @@ package puppetdb
 
 import (
 	"bytes"
-	"crypto/tls"
-	"crypto/x509"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
-	"os"
+	"net/url"
 	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"golang.org/x/net/http/httpproxy"
 )
 
 var (
 	ErrNotFound = errors.New("not found")
+	ErrReadOnly = errors.New("client is read-only, refusing to submit PuppetDB commands")
 )
 
 type Client struct {
-	URL  string
+	URL string
+
+	// URLs are the servers of a PuppetDB HA setup, which take precedence
+	// over URL. Requests go to the last server which answered and fail
+	// over to the next ones.
+	URLs    []string
+	healthy atomic.Int32
+
+	// QueryURL and CommandURL, when set, route respectively the queries
+	// and the commands to a dedicated server, such as a read replica and
+	// the primary.
+	QueryURL   string
+	CommandURL string
+
+	// CA, Cert and Key are PEM-encoded.
 	CA   string
 	Cert string
 	Key  string
+
+	// PKCS12 is a PKCS#12 bundle holding the client certificate and key,
+	// used instead of Cert and Key.
+	PKCS12 []byte
+
+	// KeyPassword decrypts Key or PKCS12.
+	KeyPassword string
+
+	// CRL is the PEM-encoded revocation list of the CA, against which the
+	// PuppetDB server certificate is checked.
+	CRL string
+
+	TLSServerName         string
+	TLSMinVersion         uint16
+	TLSInsecureSkipVerify bool
+
+	// ProxyURL is the HTTP proxy through which requests are sent, except
+	// to the hosts matched by NoProxy.
+	ProxyURL string
+	NoProxy  string
+
+	BasicAuthUsername string
+	BasicAuthPassword string
+
+	// Headers are added to every request.
+	Headers map[string]string
+
+	version      string
+	versionMutex sync.Mutex
+
+	client     *http.Client
+	clientErr  error
+	clientOnce sync.Once
+
+	Token    string
+	ReadOnly bool
+
+	// AuditLogPath is the file to which a JSON line is appended for each
+	// submitted command.
+	AuditLogPath string
+}
+
+type StatusError struct {
+	StatusCode int
+	Status     string
+	Body       []byte
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected status %s: %s", e.Status, strings.TrimSpace(string(e.Body)))
 }
 
 type Command struct {
@@ -42,90 +111,109 @@ type Node struct {
 	CatalogTimestamp             string `json:"catalog_timestamp"`
 	FactsTimestamp               string `json:"facts_timestamp"`
 	ReportTimestamp              string `json:"report_timestamp"`
-	LatestReportCorrectiveChange string `json:"latest_report_corrective_change"`
+	LatestReportCorrectiveChange *bool  `json:"latest_report_corrective_change"`
 	LatestReportHash             string `json:"latest_report_hash"`
 	LatestReportNoop             bool   `json:"latest_report_noop"`
 	LatestReportNoopPending      bool   `json:"latest_report_noop_pending"`
 	LatestReportStatus           string `json:"latest_report_status"`
 }
 
-func isFile(str string) bool {
-	return strings.HasPrefix(str, "/")
+// isCommand reports whether the query submits a command rather than
+// reading data.
+func isCommand(query string) bool {
+	return strings.HasPrefix(query, "cmd/") || strings.HasPrefix(query, "admin/")
 }
 
-func (p *Client) Query(query string, verb string, command *Command) (pdbResp *Node, err error) {
-	url := p.URL + "/pdb/" + query
+// endpoint returns the path of the query, as the status and metrics APIs are
+// not served under /pdb.
+func endpoint(query string) string {
+	if strings.HasPrefix(query, "status/") || strings.HasPrefix(query, "metrics/") {
+		return "/" + query
+	}
+
+	return "/pdb/" + query
+}
 
-	body, err := json.Marshal(command)
+func (p *Client) Query(ctx context.Context, query string, verb string, command *Command) (pdbResp *Node, err error) {
+	err = p.Do(ctx, query, verb, command, &pdbResp)
 	if err != nil {
 		return
 	}
 
-	req, err := http.NewRequest(verb, url, bytes.NewReader(body))
+	if err = pdbResp.Error; err != nil {
+		return
+	}
+
+	return pdbResp, nil
+}
+
+func (p *Client) Do(ctx context.Context, query string, verb string, payload any, pdbResp any) error {
+	_, err := p.do(ctx, query, verb, payload, pdbResp)
+
+	return err
+}
+
+func (p *Client) do(ctx context.Context, query string, verb string, payload any, pdbResp any) (statusCode int, err error) {
+	if p.ReadOnly && isCommand(query) {
+		return 0, ErrReadOnly
+	}
+
+	body, err := json.Marshal(payload)
 	if err != nil {
 		return
 	}
-	req.Header.Add("Content-Type", "application/json")
-	req.Header.Add("Accept", "application/json")
 
-	var client *http.Client
-	if p.Cert != "" {
-		// Load cert pair
-		var cert tls.Certificate
-		if isFile(p.Cert) {
-			if !isFile(p.Key) {
-				err = fmt.Errorf("cert points to a file but key is a string: %s", err)
-				return
-			}
+	client, err := p.httpClient()
+	if err != nil {
+		return
+	}
 
-			cert, err = tls.LoadX509KeyPair(p.Cert, p.Key)
-			if err != nil {
-				return pdbResp, err
-			}
-		} else {
-			if isFile(p.Key) {
-				err = fmt.Errorf("cert is a string but key points to a file: %s", err)
-				return
-			}
+	// Fail over to the next server on connection errors, and on server
+	// errors unless submitting a command, as PuppetDB answers 503 when a
+	// command was queued but not processed in time.
+	urls, healthy := p.urls(query)
 
-			cert, err = tls.X509KeyPair([]byte(p.Cert), []byte(p.Key))
-			if err != nil {
-				err = fmt.Errorf("failed to load client cert from string: %s", err)
-				return
-			}
-		}
+	var resp *http.Response
+
+	for i := range urls {
+		index := (healthy + i) % len(urls)
 
-		// Load CA cert
-		var caCert []byte
-		if isFile(p.CA) {
-			caCert, err = os.ReadFile(p.CA)
-			if err != nil {
-				return pdbResp, fmt.Errorf("failed to load CA cert at %s: %s", p.CA, err)
+		resp, err = p.send(ctx, client, urls[index]+endpoint(query), verb, body)
+
+		if err == nil && (resp.StatusCode < http.StatusInternalServerError || isCommand(query)) {
+			if index != healthy {
+				p.healthy.Store(int32(index))
 			}
-		} else {
-			caCert = []byte(p.CA)
+
+			tflog.Debug(ctx, "PuppetDB server answered", map[string]any{
+				"url":    urls[index],
+				"status": resp.StatusCode,
+			})
+
+			break
 		}
-		caCertPool := x509.NewCertPool()
-		caCertPool.AppendCertsFromPEM(caCert)
 
-		// Setup HTTPS client
-		tlsConfig := &tls.Config{
-			Certificates: []tls.Certificate{cert},
-			RootCAs:      caCertPool,
+		if i < len(urls)-1 {
+			tflog.Warn(ctx, "PuppetDB server failed, failing over to the next one", map[string]any{
+				"url":   urls[index],
+				"error": failoverReason(resp, err),
+			})
+
+			if err == nil {
+				resp.Body.Close()
+			}
 		}
-		transport := &http.Transport{TLSClientConfig: tlsConfig}
-		client = &http.Client{Transport: transport}
-	} else {
-		client = &http.Client{}
 	}
 
-	resp, err := client.Do(req)
 	if err != nil {
 		return
 	}
+	defer resp.Body.Close()
+
+	statusCode = resp.StatusCode
 
 	if resp.StatusCode == http.StatusNotFound {
-		return nil, ErrNotFound
+		return statusCode, ErrNotFound
 	}
 
 	body, err = io.ReadAll(resp.Body)
@@ -133,11 +221,100 @@ func (p *Client) Query(query string, verb string, command *Command) (pdbResp *No
 		return
 	}
 
-	json.Unmarshal(body, &pdbResp)
+	if resp.StatusCode >= http.StatusBadRequest {
+		return statusCode, &StatusError{
+			StatusCode: resp.StatusCode,
+			Status:     resp.Status,
+			Body:       body,
+		}
+	}
+
+	return statusCode, json.Unmarshal(body, pdbResp)
+}
 
-	if err = pdbResp.Error; err != nil {
-		return
+func (p *Client) send(ctx context.Context, client *http.Client, url string, verb string, body []byte) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, verb, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
 	}
+	req.Header.Add("Content-Type", "application/json")
+	req.Header.Add("Accept", "application/json")
 
-	return pdbResp, nil
+	for name, value := range p.Headers {
+		req.Header.Set(name, value)
+	}
+
+	if p.Token != "" {
+		req.Header.Add("X-Authentication", p.Token)
+	}
+
+	if p.BasicAuthUsername != "" {
+		req.SetBasicAuth(p.BasicAuthUsername, p.BasicAuthPassword)
+	}
+
+	return client.Do(req)
+}
+
+// urls returns the servers to which the query can be sent, along with the
+// index of the one to try first.
+func (p *Client) urls(query string) ([]string, int) {
+	if isCommand(query) && p.CommandURL != "" {
+		return []string{p.CommandURL}, 0
+	}
+
+	if !isCommand(query) && p.QueryURL != "" {
+		return []string{p.QueryURL}, 0
+	}
+
+	if len(p.URLs) > 0 {
+		return p.URLs, int(p.healthy.Load())
+	}
+
+	return []string{p.URL}, 0
+}
+
+func failoverReason(resp *http.Response, err error) string {
+	if err != nil {
+		return err.Error()
+	}
+
+	return resp.Status
+}
+
+// httpClient returns the HTTP client of p, which is created once so that
+// connections are reused across requests.
+func (p *Client) httpClient() (*http.Client, error) {
+	p.clientOnce.Do(func() {
+		p.client, p.clientErr = p.newHTTPClient()
+	})
+
+	return p.client, p.clientErr
+}
+
+func (p *Client) newHTTPClient() (*http.Client, error) {
+	tlsConfig, err := p.TLSConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	// Setup HTTPS client, with the dial and TLS handshake timeouts of the
+	// default transport so that unreachable servers fail over quickly, and
+	// its proxy from the environment unless ProxyURL is set
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	transport.Proxy = http.ProxyFromEnvironment
+
+	if p.ProxyURL != "" {
+		proxyFunc := (&httpproxy.Config{
+			HTTPProxy:  p.ProxyURL,
+			HTTPSProxy: p.ProxyURL,
+			NoProxy:    p.NoProxy,
+		}).ProxyFunc()
+
+		transport.Proxy = func(req *http.Request) (*url.URL, error) {
+			return proxyFunc(req.URL)
+		}
+	}
+
+	return &http.Client{Transport: transport}, nil
 }
@@ -0,0 +1,232 @@
+package puppetdb
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestQueryNode(t *testing.T) {
+	tests := []struct {
+		name                 string
+		body                 string
+		wantCorrectiveChange *bool
+	}{
+		{
+			name: "puppet enterprise",
+			body: `{
+				"certname": "foo.example.com",
+				"deactivated": null,
+				"expired": null,
+				"cached_catalog_status": "not_used",
+				"catalog_environment": "production",
+				"facts_environment": "production",
+				"report_environment": "production",
+				"catalog_timestamp": "2023-10-30T10:00:00.000Z",
+				"facts_timestamp": "2023-10-30T10:00:00.000Z",
+				"report_timestamp": "2023-10-30T10:00:00.000Z",
+				"latest_report_corrective_change": false,
+				"latest_report_hash": "0123456789abcdef",
+				"latest_report_noop": false,
+				"latest_report_noop_pending": false,
+				"latest_report_status": "unchanged",
+				"latest_report_job_id": null
+			}`,
+			wantCorrectiveChange: new(bool),
+		},
+		{
+			name: "open source",
+			body: `{
+				"certname": "foo.example.com",
+				"catalog_environment": "production",
+				"latest_report_corrective_change": null,
+				"latest_report_status": "unchanged"
+			}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/pdb/query/v4/nodes/foo.example.com" {
+					http.NotFound(w, r)
+
+					return
+				}
+
+				fmt.Fprint(w, tt.body)
+			}))
+			defer server.Close()
+
+			client := &Client{URL: server.URL}
+
+			node, err := client.Query(context.Background(), "query/v4/nodes/foo.example.com", "GET", nil)
+			if err != nil {
+				t.Fatalf("Query() failed: %v", err)
+			}
+
+			if node.Certname != "foo.example.com" || node.CatalogEnvironment != "production" || node.LatestReportStatus != "unchanged" {
+				t.Errorf("Query() = %+v, want the node fields decoded", node)
+			}
+
+			switch {
+			case tt.wantCorrectiveChange == nil && node.LatestReportCorrectiveChange != nil:
+				t.Errorf("LatestReportCorrectiveChange = %v, want nil", *node.LatestReportCorrectiveChange)
+			case tt.wantCorrectiveChange != nil && (node.LatestReportCorrectiveChange == nil || *node.LatestReportCorrectiveChange != *tt.wantCorrectiveChange):
+				t.Errorf("LatestReportCorrectiveChange = %v, want %v", node.LatestReportCorrectiveChange, *tt.wantCorrectiveChange)
+			}
+		})
+	}
+}
+
+// bodyTracker wraps a transport to record how many response bodies were
+// left open.
+type bodyTracker struct {
+	transport http.RoundTripper
+	open      atomic.Int32
+}
+
+func (b *bodyTracker) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := b.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	b.open.Add(1)
+	resp.Body = &trackedBody{ReadCloser: resp.Body, tracker: b}
+
+	return resp, nil
+}
+
+type trackedBody struct {
+	io.ReadCloser
+	tracker *bodyTracker
+	closed  bool
+}
+
+func (b *trackedBody) Close() error {
+	if !b.closed {
+		b.closed = true
+		b.tracker.open.Add(-1)
+	}
+
+	return b.ReadCloser.Close()
+}
+
+// newTrackedClient returns a client for the URLs whose response bodies are
+// tracked.
+func newTrackedClient(urls ...string) (*Client, *bodyTracker) {
+	tracker := &bodyTracker{transport: http.DefaultTransport}
+	client := &Client{URL: urls[0], URLs: urls}
+
+	client.clientOnce.Do(func() {
+		client.client = &http.Client{Transport: tracker}
+	})
+
+	return client, tracker
+}
+
+// countingServer answers every request with the given status and counts the
+// requests it received.
+func countingServer(t *testing.T, status int) (*httptest.Server, *atomic.Int32) {
+	var hits atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.WriteHeader(status)
+		fmt.Fprint(w, `{"certname": "foo.example.com"}`)
+	}))
+	t.Cleanup(server.Close)
+
+	return server, &hits
+}
+
+func TestFailover(t *testing.T) {
+	down := httptest.NewServer(http.NotFoundHandler())
+	downURL := down.URL
+	down.Close()
+
+	tests := []struct {
+		name        string
+		firstStatus int
+		firstDown   bool
+		query       string
+		wantErr     bool
+		wantHealthy int32
+		wantHits    [2]int32
+	}{
+		{
+			name:        "connection error",
+			firstDown:   true,
+			query:       "query/v4/nodes/foo.example.com",
+			wantHealthy: 1,
+			wantHits:    [2]int32{0, 2},
+		},
+		{
+			name:        "server error",
+			firstStatus: http.StatusInternalServerError,
+			query:       "query/v4/nodes/foo.example.com",
+			wantHealthy: 1,
+			wantHits:    [2]int32{1, 2},
+		},
+		{
+			name:        "healthy server",
+			firstStatus: http.StatusOK,
+			query:       "query/v4/nodes/foo.example.com",
+			wantHealthy: 0,
+			wantHits:    [2]int32{2, 0},
+		},
+		{
+			name:        "command server error",
+			firstStatus: http.StatusServiceUnavailable,
+			query:       "cmd/v1?command=deactivate_node",
+			wantErr:     true,
+			wantHealthy: 0,
+			wantHits:    [2]int32{2, 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first, firstHits := countingServer(t, tt.firstStatus)
+			second, secondHits := countingServer(t, http.StatusOK)
+
+			firstURL := first.URL
+			if tt.firstDown {
+				firstURL = downURL
+			}
+
+			client, tracker := newTrackedClient(firstURL, second.URL)
+
+			// The second request checks that the healthy server is
+			// remembered rather than retrying the failing one first.
+			for i := 0; i < 2; i++ {
+				err := client.Do(context.Background(), tt.query, "GET", nil, &map[string]any{})
+
+				if tt.wantErr && err == nil {
+					t.Fatalf("request %d: Do() succeeded, want an error", i)
+				}
+
+				if !tt.wantErr && err != nil {
+					t.Fatalf("request %d: Do() failed: %v", i, err)
+				}
+			}
+
+			if healthy := client.healthy.Load(); healthy != tt.wantHealthy {
+				t.Errorf("healthy = %d, want %d", healthy, tt.wantHealthy)
+			}
+
+			if hits := [2]int32{firstHits.Load(), secondHits.Load()}; hits != tt.wantHits {
+				t.Errorf("hits = %v, want %v", hits, tt.wantHits)
+			}
+
+			if open := tracker.open.Load(); open != 0 {
+				t.Errorf("%d response bodies left open", open)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package puppetdb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type CommandResult struct {
+	UUID      string `json:"uuid"`
+	Processed bool   `json:"processed"`
+	TimedOut  bool   `json:"timed_out"`
+}
+
+// SubmitCommand submits a command for the given certname. When
+// secondsToWaitForCompletion is positive, PuppetDB holds the response until
+// the command is processed or the delay expires, in which case the result
+// is flagged as timed out.
+func (p *Client) SubmitCommand(ctx context.Context, command *Command, certname string, producerTimestamp time.Time, secondsToWaitForCompletion int) (*CommandResult, error) {
+	params := url.Values{
+		"command":            {strings.ReplaceAll(command.Command, " ", "_")},
+		"version":            {strconv.Itoa(command.Version)},
+		"certname":           {certname},
+		"producer-timestamp": {producerTimestamp.Format(time.RFC3339Nano)},
+	}
+
+	if secondsToWaitForCompletion > 0 {
+		params.Set("secondsToWaitForCompletion", strconv.Itoa(secondsToWaitForCompletion))
+	}
+
+	var result CommandResult
+
+	statusCode, err := p.do(ctx, "cmd/v1?"+params.Encode(), "POST", command.Payload, &result)
+
+	if errors.Is(err, ErrReadOnly) {
+		return nil, err
+	}
+
+	var statusErr *StatusError
+
+	if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusServiceUnavailable {
+		if json.Unmarshal(statusErr.Body, &result) == nil && result.TimedOut {
+			err = nil
+		}
+	}
+
+	auditErr := p.audit(&AuditRecord{
+		Time:              time.Now().UTC(),
+		Command:           command.Command,
+		Version:           command.Version,
+		Certname:          certname,
+		ProducerTimestamp: &producerTimestamp,
+		Status:            statusCode,
+		UUID:              result.UUID,
+		Error:             errorString(err),
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if auditErr != nil {
+		return nil, fmt.Errorf("failed to write audit log for command %q on %s: %w", command.Command, certname, auditErr)
+	}
+
+	return &result, nil
+}
+
+// SubmitAdminCommand submits a command for the given certname to the admin
+// API, such as the delete command purging all of its data.
+func (p *Client) SubmitAdminCommand(ctx context.Context, command *Command, certname string) error {
+	statusCode, err := p.do(ctx, "admin/v1/cmd", "POST", command, &map[string]any{})
+
+	if errors.Is(err, ErrReadOnly) {
+		return err
+	}
+
+	auditErr := p.audit(&AuditRecord{
+		Time:     time.Now().UTC(),
+		Command:  command.Command,
+		Version:  command.Version,
+		Certname: certname,
+		Status:   statusCode,
+		Error:    errorString(err),
+	})
+
+	if err != nil {
+		return err
+	}
+
+	if auditErr != nil {
+		return fmt.Errorf("failed to write audit log for admin command %q on %s: %w", command.Command, certname, auditErr)
+	}
+
+	return nil
+}
@@ -0,0 +1,27 @@
+package puppetdb
+
+import (
+	"encoding/json"
+)
+
+type Fact struct {
+	Certname    string `json:"certname"`
+	Environment string `json:"environment"`
+	Name        string `json:"name"`
+	Value       any    `json:"value"`
+}
+
+// ValueString returns the fact value as a string, JSON-encoding
+// structured and non-string values.
+func (f *Fact) ValueString() string {
+	if value, ok := f.Value.(string); ok {
+		return value
+	}
+
+	value, err := json.Marshal(f.Value)
+	if err != nil {
+		return ""
+	}
+
+	return string(value)
+}
@@ -0,0 +1,120 @@
+package puppetdb
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type Version struct {
+	Version string `json:"version"`
+}
+
+type ServerTime struct {
+	ServerTime string `json:"server_time"`
+}
+
+type UnsupportedVersionError struct {
+	Feature    string
+	Version    string
+	MinVersion string
+}
+
+func (e *UnsupportedVersionError) Error() string {
+	return fmt.Sprintf("%s requires PuppetDB >= %s, but the server runs PuppetDB %s", e.Feature, e.MinVersion, e.Version)
+}
+
+// Version returns the PuppetDB version, which is only queried once per
+// client.
+func (p *Client) Version(ctx context.Context) (string, error) {
+	p.versionMutex.Lock()
+	defer p.versionMutex.Unlock()
+
+	if p.version != "" {
+		return p.version, nil
+	}
+
+	var version Version
+
+	if err := p.Do(ctx, "meta/v1/version", "GET", nil, &version); err != nil {
+		return "", err
+	}
+
+	p.version = version.Version
+
+	return p.version, nil
+}
+
+func (p *Client) ServerTime(ctx context.Context) (string, error) {
+	var serverTime ServerTime
+
+	err := p.Do(ctx, "meta/v1/server-time", "GET", nil, &serverTime)
+
+	return serverTime.ServerTime, err
+}
+
+// RequireVersion fails with an UnsupportedVersionError when the server runs
+// a PuppetDB older than minVersion.
+func (p *Client) RequireVersion(ctx context.Context, feature string, minVersion string) error {
+	version, err := p.Version(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get PuppetDB version: %w", err)
+	}
+
+	if compareVersions(version, minVersion) < 0 {
+		return &UnsupportedVersionError{
+			Feature:    feature,
+			Version:    version,
+			MinVersion: minVersion,
+		}
+	}
+
+	return nil
+}
+
+// compareVersions compares the numeric components of two versions such as
+// "7.13.0", ignoring any suffix like "-SNAPSHOT".
+func compareVersions(a string, b string) int {
+	aParts := versionParts(a)
+	bParts := versionParts(b)
+
+	for i := 0; i < len(aParts) || i < len(bParts); i++ {
+		var aPart, bPart int
+
+		if i < len(aParts) {
+			aPart = aParts[i]
+		}
+
+		if i < len(bParts) {
+			bPart = bParts[i]
+		}
+
+		if aPart != bPart {
+			if aPart < bPart {
+				return -1
+			}
+
+			return 1
+		}
+	}
+
+	return 0
+}
+
+func versionParts(version string) []int {
+	version, _, _ = strings.Cut(version, "-")
+
+	var parts []int
+
+	for _, part := range strings.Split(version, ".") {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			break
+		}
+
+		parts = append(parts, n)
+	}
+
+	return parts
+}
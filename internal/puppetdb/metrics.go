@@ -0,0 +1,59 @@
+package puppetdb
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// MBean is the Jolokia response to reading an MBean.
+type MBean struct {
+	Status int                        `json:"status"`
+	Error  string                     `json:"error"`
+	Value  map[string]json.RawMessage `json:"value"`
+}
+
+// jolokiaEscaper escapes the characters with a special meaning in Jolokia
+// GET URLs.
+var jolokiaEscaper = strings.NewReplacer("!", "!!", "/", "!/", `"`, `!"`)
+
+// ReadMBean returns the attributes of the MBean, with string values
+// unquoted and other values encoded as JSON.
+func (p *Client) ReadMBean(ctx context.Context, name string) (map[string]string, error) {
+	segments := strings.Split(jolokiaEscaper.Replace(name), "/")
+
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+
+	var mbean MBean
+
+	if err := p.Do(ctx, "metrics/v2/read/"+strings.Join(segments, "/"), "GET", nil, &mbean); err != nil {
+		return nil, err
+	}
+
+	// Jolokia reports errors in the body rather than with the HTTP status.
+	if mbean.Status != 200 {
+		if mbean.Status == 404 {
+			return nil, fmt.Errorf("mbean %s: %w", name, ErrNotFound)
+		}
+
+		return nil, fmt.Errorf("failed to read mbean %s: %s", name, mbean.Error)
+	}
+
+	attributes := make(map[string]string, len(mbean.Value))
+
+	for attribute, value := range mbean.Value {
+		var s string
+
+		if err := json.Unmarshal(value, &s); err != nil {
+			s = string(value)
+		}
+
+		attributes[attribute] = s
+	}
+
+	return attributes, nil
+}
@@ -0,0 +1,16 @@
+package puppetdb
+
+import (
+	"encoding/json"
+	"net/url"
+)
+
+// QueryPath appends the given AST query to a query endpoint.
+func QueryPath(endpoint string, query []any) (string, error) {
+	encodedQuery, err := json.Marshal(query)
+	if err != nil {
+		return "", err
+	}
+
+	return endpoint + "?query=" + url.QueryEscape(string(encodedQuery)), nil
+}
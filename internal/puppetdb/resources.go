@@ -0,0 +1,14 @@
+package puppetdb
+
+type Resource struct {
+	Certname    string         `json:"certname"`
+	Environment string         `json:"environment"`
+	Resource    string         `json:"resource"`
+	Type        string         `json:"type"`
+	Title       string         `json:"title"`
+	Exported    bool           `json:"exported"`
+	Tags        []string       `json:"tags"`
+	File        string         `json:"file"`
+	Line        int            `json:"line"`
+	Parameters  map[string]any `json:"parameters"`
+}
@@ -0,0 +1,65 @@
+package puppetdb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+type ServiceStatus struct {
+	ServiceVersion string          `json:"service_version"`
+	State          string          `json:"state"`
+	Status         json.RawMessage `json:"status"`
+}
+
+type PuppetDBStatus struct {
+	MaintenanceMode bool  `json:"maintenance_mode?"`
+	QueueDepth      int64 `json:"queue_depth"`
+	ReadDBUp        bool  `json:"read_db_up?"`
+	WriteDBUp       bool  `json:"write_db_up?"`
+}
+
+// Services returns the status of every service running on the PuppetDB
+// server.
+func (p *Client) Services(ctx context.Context) (map[string]ServiceStatus, error) {
+	var services map[string]ServiceStatus
+
+	err := p.status(ctx, "status/v1/services", &services)
+
+	return services, err
+}
+
+// PuppetDBStatus returns the status of the puppetdb-status service along
+// with its details.
+func (p *Client) PuppetDBStatus(ctx context.Context) (*ServiceStatus, *PuppetDBStatus, error) {
+	var service ServiceStatus
+
+	if err := p.status(ctx, "status/v1/services/puppetdb-status", &service); err != nil {
+		return nil, nil, err
+	}
+
+	var status PuppetDBStatus
+
+	if len(service.Status) > 0 {
+		if err := json.Unmarshal(service.Status, &status); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return &service, &status, nil
+}
+
+// status queries the status API, which answers 503 along with the status
+// when a service is not running.
+func (p *Client) status(ctx context.Context, query string, pdbResp any) error {
+	err := p.Do(ctx, query, "GET", nil, pdbResp)
+
+	var statusErr *StatusError
+
+	if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusServiceUnavailable {
+		return json.Unmarshal(statusErr.Body, pdbResp)
+	}
+
+	return err
+}
@@ -0,0 +1,262 @@
+package puppetdb
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/youmark/pkcs8"
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+// TLSConfig builds the TLS configuration from the PEM-encoded CA and either
+// the PEM-encoded client certificate and key or the PKCS#12 bundle.
+func (p *Client) TLSConfig() (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		ServerName:         p.TLSServerName,
+		MinVersion:         p.TLSMinVersion,
+		InsecureSkipVerify: p.TLSInsecureSkipVerify,
+	}
+
+	var caCerts []*x509.Certificate
+
+	switch {
+	case len(p.PKCS12) > 0:
+		cert, bundledCACerts, err := loadPKCS12(p.PKCS12, p.KeyPassword)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load PKCS#12 bundle: %s", err)
+		}
+
+		tlsConfig.Certificates = []tls.Certificate{cert}
+		caCerts = bundledCACerts
+	case p.Cert != "" || p.Key != "":
+		// Load cert pair
+		cert, err := loadKeyPair(p.Cert, p.Key, p.KeyPassword)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %s", err)
+		}
+
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	if p.CA != "" {
+		// Load CA cert
+		caCertPool := x509.NewCertPool()
+		if !caCertPool.AppendCertsFromPEM([]byte(p.CA)) {
+			return nil, fmt.Errorf("failed to load CA certificate: no certificate found")
+		}
+
+		tlsConfig.RootCAs = caCertPool
+		caCerts = ParseCertificates(p.CA)
+	} else if len(caCerts) > 0 {
+		// Fall back to the CA certificates shipped in the PKCS#12 bundle
+		caCertPool := x509.NewCertPool()
+		for _, caCert := range caCerts {
+			caCertPool.AddCert(caCert)
+		}
+
+		tlsConfig.RootCAs = caCertPool
+	}
+
+	if p.CRL != "" {
+		crl, err := loadCRL(p.CRL, caCerts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load CRL: %w", err)
+		}
+
+		tlsConfig.VerifyConnection = func(cs tls.ConnectionState) error {
+			if err := checkCRLExpiry(crl, time.Now()); err != nil {
+				return err
+			}
+
+			return checkRevocation(cs.PeerCertificates, crl)
+		}
+	}
+
+	return tlsConfig, nil
+}
+
+type CertificateRevokedError struct {
+	Subject      string
+	SerialNumber *big.Int
+	RevokedAt    time.Time
+}
+
+func (e *CertificateRevokedError) Error() string {
+	return fmt.Sprintf("certificate %s (serial %s) was revoked on %s", e.Subject, e.SerialNumber, e.RevokedAt.Format(time.RFC3339))
+}
+
+type CRLExpiredError struct {
+	Issuer     string
+	NextUpdate time.Time
+}
+
+func (e *CRLExpiredError) Error() string {
+	return fmt.Sprintf("CRL of %s expired on %s", e.Issuer, e.NextUpdate.Format(time.RFC3339))
+}
+
+// loadCRL parses the PEM-encoded CRL and checks that it is still valid and
+// was signed by one of the CA certificates.
+func loadCRL(crlPEM string, caCerts []*x509.Certificate) (*x509.RevocationList, error) {
+	block, _ := pem.Decode([]byte(crlPEM))
+	if block == nil || block.Type != "X509 CRL" {
+		return nil, errors.New("no X509 CRL found")
+	}
+
+	crl, err := x509.ParseRevocationList(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkCRLExpiry(crl, time.Now()); err != nil {
+		return nil, err
+	}
+
+	if len(caCerts) == 0 {
+		return nil, errors.New("a CA certificate is required to verify the CRL")
+	}
+
+	for _, caCert := range caCerts {
+		if crl.CheckSignatureFrom(caCert) == nil {
+			return crl, nil
+		}
+	}
+
+	return nil, errors.New("CRL is not signed by the configured CA")
+}
+
+// checkCRLExpiry fails if the CRL should have been replaced by a newer one
+// by now.
+func checkCRLExpiry(crl *x509.RevocationList, now time.Time) error {
+	if !crl.NextUpdate.IsZero() && now.After(crl.NextUpdate) {
+		return &CRLExpiredError{
+			Issuer:     crl.Issuer.String(),
+			NextUpdate: crl.NextUpdate,
+		}
+	}
+
+	return nil
+}
+
+// ParseCertificates returns the certificates found in the PEM data,
+// skipping any block which fails to parse.
+func ParseCertificates(data string) []*x509.Certificate {
+	var certs []*x509.Certificate
+
+	for rest := []byte(data); ; {
+		var block *pem.Block
+
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			return certs
+		}
+
+		if cert, err := x509.ParseCertificate(block.Bytes); err == nil {
+			certs = append(certs, cert)
+		}
+	}
+}
+
+// checkRevocation fails if any of the certificates issued by the CRL issuer
+// is revoked.
+func checkRevocation(certs []*x509.Certificate, crl *x509.RevocationList) error {
+	for _, cert := range certs {
+		if !bytes.Equal(cert.RawIssuer, crl.RawIssuer) {
+			continue
+		}
+
+		for _, entry := range crl.RevokedCertificateEntries {
+			if entry.SerialNumber.Cmp(cert.SerialNumber) == 0 {
+				return &CertificateRevokedError{
+					Subject:      cert.Subject.String(),
+					SerialNumber: cert.SerialNumber,
+					RevokedAt:    entry.RevocationTime,
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func loadKeyPair(certPEM string, keyPEM string, password string) (tls.Certificate, error) {
+	block, _ := pem.Decode([]byte(keyPEM))
+	if block == nil {
+		return tls.Certificate{}, errors.New("no private key found")
+	}
+
+	// Legacy encrypted PEM keys ("Proc-Type: 4,ENCRYPTED") are insecure but
+	// still produced by some tools, so they are supported alongside PKCS#8.
+	isLegacyEncrypted := x509.IsEncryptedPEMBlock(block)
+
+	if block.Type != "ENCRYPTED PRIVATE KEY" && !isLegacyEncrypted {
+		return tls.X509KeyPair([]byte(certPEM), []byte(keyPEM))
+	}
+
+	if password == "" {
+		return tls.Certificate{}, errors.New("private key is encrypted but no key password is set")
+	}
+
+	var key any
+	var err error
+
+	if isLegacyEncrypted {
+		var der []byte
+
+		der, err = x509.DecryptPEMBlock(block, []byte(password))
+		if err != nil {
+			return tls.Certificate{}, fmt.Errorf("failed to decrypt private key: %s", err)
+		}
+
+		key, err = parsePrivateKey(der)
+	} else {
+		key, err = pkcs8.ParsePKCS8PrivateKey(block.Bytes, []byte(password))
+	}
+
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("failed to decrypt private key: %s", err)
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	return tls.X509KeyPair([]byte(certPEM), pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+}
+
+func parsePrivateKey(der []byte) (any, error) {
+	if key, err := x509.ParsePKCS1PrivateKey(der); err == nil {
+		return key, nil
+	}
+
+	if key, err := x509.ParseECPrivateKey(der); err == nil {
+		return key, nil
+	}
+
+	return x509.ParsePKCS8PrivateKey(der)
+}
+
+func loadPKCS12(data []byte, password string) (tls.Certificate, []*x509.Certificate, error) {
+	key, cert, caCerts, err := pkcs12.DecodeChain(data, password)
+	if err != nil {
+		return tls.Certificate{}, nil, err
+	}
+
+	tlsCert := tls.Certificate{
+		Certificate: [][]byte{cert.Raw},
+		PrivateKey:  key,
+		Leaf:        cert,
+	}
+
+	for _, caCert := range caCerts {
+		tlsCert.Certificate = append(tlsCert.Certificate, caCert.Raw)
+	}
+
+	return tlsCert, caCerts, nil
+}
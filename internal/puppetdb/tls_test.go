@@ -0,0 +1,208 @@
+package puppetdb
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCA(t *testing.T, name string) *testCA {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &testCA{cert: cert, key: key}
+}
+
+func (ca *testCA) issue(t *testing.T, serial int64) *x509.Certificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "puppetdb.example.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return cert
+}
+
+func (ca *testCA) crl(t *testing.T, nextUpdate time.Time, revokedSerials ...int64) string {
+	t.Helper()
+
+	var entries []x509.RevocationListEntry
+
+	for _, serial := range revokedSerials {
+		entries = append(entries, x509.RevocationListEntry{
+			SerialNumber:   big.NewInt(serial),
+			RevocationTime: time.Now().Add(-time.Minute),
+		})
+	}
+
+	der, err := x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+		Number:                    big.NewInt(1),
+		ThisUpdate:                time.Now().Add(-2 * time.Hour),
+		NextUpdate:                nextUpdate,
+		RevokedCertificateEntries: entries,
+	}, ca.cert, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(pem.EncodeToMemory(&pem.Block{Type: "X509 CRL", Bytes: der}))
+}
+
+func TestLoadCRL(t *testing.T) {
+	ca := newTestCA(t, "Puppet CA")
+	otherCA := newTestCA(t, "Other CA")
+
+	tests := []struct {
+		name        string
+		crl         string
+		caCerts     []*x509.Certificate
+		wantErr     bool
+		wantExpired bool
+	}{
+		{
+			name:    "signed by the CA",
+			crl:     ca.crl(t, time.Now().Add(time.Hour)),
+			caCerts: []*x509.Certificate{otherCA.cert, ca.cert},
+		},
+		{
+			name:    "not signed by the CA",
+			crl:     ca.crl(t, time.Now().Add(time.Hour)),
+			caCerts: []*x509.Certificate{otherCA.cert},
+			wantErr: true,
+		},
+		{
+			name:    "no CA",
+			crl:     ca.crl(t, time.Now().Add(time.Hour)),
+			wantErr: true,
+		},
+		{
+			name:        "expired",
+			crl:         ca.crl(t, time.Now().Add(-time.Minute)),
+			caCerts:     []*x509.Certificate{ca.cert},
+			wantErr:     true,
+			wantExpired: true,
+		},
+		{
+			name:    "not a CRL",
+			crl:     string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.cert.Raw})),
+			caCerts: []*x509.Certificate{ca.cert},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadCRL(tt.crl, tt.caCerts)
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadCRL() error = %v, want error %t", err, tt.wantErr)
+			}
+
+			var expiredErr *CRLExpiredError
+
+			if errors.As(err, &expiredErr) != tt.wantExpired {
+				t.Errorf("loadCRL() error = %v, want CRLExpiredError %t", err, tt.wantExpired)
+			}
+		})
+	}
+}
+
+func TestCheckRevocation(t *testing.T) {
+	ca := newTestCA(t, "Puppet CA")
+	otherCA := newTestCA(t, "Other CA")
+
+	crl, err := loadCRL(ca.crl(t, time.Now().Add(time.Hour), 42), []*x509.Certificate{ca.cert})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		certs       []*x509.Certificate
+		wantRevoked bool
+	}{
+		{
+			name:        "revoked serial",
+			certs:       []*x509.Certificate{ca.issue(t, 42), ca.cert},
+			wantRevoked: true,
+		},
+		{
+			name:  "valid serial",
+			certs: []*x509.Certificate{ca.issue(t, 43), ca.cert},
+		},
+		{
+			name:  "same serial from another issuer",
+			certs: []*x509.Certificate{otherCA.issue(t, 42)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkRevocation(tt.certs, crl)
+
+			var revokedErr *CertificateRevokedError
+
+			if errors.As(err, &revokedErr) != tt.wantRevoked {
+				t.Fatalf("checkRevocation() error = %v, want CertificateRevokedError %t", err, tt.wantRevoked)
+			}
+
+			if tt.wantRevoked && revokedErr.SerialNumber.Int64() != 42 {
+				t.Errorf("revoked serial = %s, want 42", revokedErr.SerialNumber)
+			}
+		})
+	}
+}
@@ -3,40 +3,88 @@ package resources
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/camptocamp/terraform-provider-puppetdb/internal/log"
 	"github.com/camptocamp/terraform-provider-puppetdb/internal/provider"
 	"github.com/camptocamp/terraform-provider-puppetdb/internal/puppetdb"
+	"github.com/camptocamp/terraform-provider-puppetdb/internal/validators"
 	"github.com/cenkalti/backoff/v4"
+	"github.com/hashicorp/terraform-plugin-framework-validators/int64validator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
 	"github.com/hashicorp/terraform-plugin-framework/types"
 	"github.com/hashicorp/terraform-plugin-log/tflog"
 )
 
+const (
+	onDestroyDeactivate = "deactivate"
+	onDestroyNone       = "none"
+	onDestroyPurge      = "purge"
+)
+
+const (
+	onDeactivatedWait   = "wait"
+	onDeactivatedFail   = "fail"
+	onDeactivatedAccept = "accept"
+)
+
+const (
+	onReadDeactivatedRemove = "remove"
+	onReadDeactivatedWarn   = "warn"
+)
+
+const (
+	secondsToWaitForDeactivation = 30
+)
+
 type Node struct {
 	provider *provider.Provider
 }
 
 type NodeModel struct {
-	CertificateName              types.String `tfsdk:"certname"`
-	Deactivated                  types.String `tfsdk:"deactivated"`
-	Expired                      types.String `tfsdk:"expired"`
-	CachedCatalogStatus          types.String `tfsdk:"cached_catalog_status"`
-	CatalogEnvironment           types.String `tfsdk:"catalog_environment"`
-	FactsEnvironment             types.String `tfsdk:"facts_environment"`
-	ReportEnvironment            types.String `tfsdk:"report_environment"`
-	CatalogTimestamp             types.String `tfsdk:"catalog_timestamp"`
-	FactsTimestamp               types.String `tfsdk:"facts_timestamp"`
-	ReportTimestamp              types.String `tfsdk:"report_timestamp"`
-	LatestReportCorrectiveChange types.String `tfsdk:"latest_report_corrective_change"`
-	LatestReportHash             types.String `tfsdk:"latest_report_hash"`
-	LatestReportNoop             types.Bool   `tfsdk:"latest_report_noop"`
-	LatestReportNoopPending      types.Bool   `tfsdk:"latest_report_noop_pending"`
-	LatestReportStatus           types.String `tfsdk:"latest_report_status"`
+	CertificateName                types.String          `tfsdk:"certname"`
+	Deactivated                    types.String          `tfsdk:"deactivated"`
+	Expired                        types.String          `tfsdk:"expired"`
+	CachedCatalogStatus            types.String          `tfsdk:"cached_catalog_status"`
+	CatalogEnvironment             types.String          `tfsdk:"catalog_environment"`
+	FactsEnvironment               types.String          `tfsdk:"facts_environment"`
+	ReportEnvironment              types.String          `tfsdk:"report_environment"`
+	CatalogTimestamp               types.String          `tfsdk:"catalog_timestamp"`
+	FactsTimestamp                 types.String          `tfsdk:"facts_timestamp"`
+	ReportTimestamp                types.String          `tfsdk:"report_timestamp"`
+	LatestReportCorrectiveChange   types.String          `tfsdk:"latest_report_corrective_change"`
+	LatestReportHash               types.String          `tfsdk:"latest_report_hash"`
+	LatestReportNoop               types.Bool            `tfsdk:"latest_report_noop"`
+	LatestReportNoopPending        types.Bool            `tfsdk:"latest_report_noop_pending"`
+	LatestReportStatus             types.String          `tfsdk:"latest_report_status"`
+	WaitForFacts                   []WaitForFactModel    `tfsdk:"wait_for_facts"`
+	Facts                          types.Map             `tfsdk:"facts"`
+	WaitForCatalog                 []WaitForCatalogModel `tfsdk:"wait_for_catalog"`
+	OnDestroy                      types.String          `tfsdk:"on_destroy"`
+	PreventDestroyIfReportedWithin types.String          `tfsdk:"prevent_destroy_if_reported_within"`
+	OnReadDeactivated              types.String          `tfsdk:"on_read_deactivated"`
+	OnDeactivated                  types.String          `tfsdk:"on_deactivated"`
+	ExpectedEnvironment            types.String          `tfsdk:"expected_environment"`
+	MaxQueueDepth                  types.Int64           `tfsdk:"max_queue_depth"`
+}
+
+type WaitForFactModel struct {
+	Name  types.String `tfsdk:"name"`
+	Value types.String `tfsdk:"value"`
+}
+
+type WaitForCatalogModel struct {
+	Type  types.String `tfsdk:"type"`
+	Title types.String `tfsdk:"title"`
 }
 
 func (r *Node) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
@@ -95,12 +143,120 @@ func (r *Node) Schema(ctx context.Context, req resource.SchemaRequest, resp *res
 			"latest_report_status": schema.StringAttribute{
 				Computed: true,
 			},
+			"wait_for_facts": schema.ListNestedAttribute{
+				Optional: true,
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"name": schema.StringAttribute{
+							Required: true,
+						},
+						"value": schema.StringAttribute{
+							Optional: true,
+						},
+					},
+				},
+			},
+			"facts": schema.MapAttribute{
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"on_destroy": schema.StringAttribute{
+				Optional: true,
+				Validators: []validator.String{
+					stringvalidator.OneOf(onDestroyDeactivate, onDestroyNone, onDestroyPurge),
+				},
+			},
+			"prevent_destroy_if_reported_within": schema.StringAttribute{
+				Optional: true,
+				Validators: []validator.String{
+					validators.Duration(),
+				},
+			},
+			"on_read_deactivated": schema.StringAttribute{
+				Optional: true,
+				Validators: []validator.String{
+					stringvalidator.OneOf(onReadDeactivatedRemove, onReadDeactivatedWarn),
+				},
+			},
+			"on_deactivated": schema.StringAttribute{
+				Optional: true,
+				Validators: []validator.String{
+					stringvalidator.OneOf(onDeactivatedWait, onDeactivatedFail, onDeactivatedAccept),
+				},
+			},
+			"expected_environment": schema.StringAttribute{
+				Optional: true,
+			},
+			"max_queue_depth": schema.Int64Attribute{
+				Optional: true,
+				Validators: []validator.Int64{
+					int64validator.AtLeast(0),
+				},
+			},
 		},
+		Blocks: map[string]schema.Block{
+			"wait_for_catalog": schema.ListNestedBlock{
+				NestedObject: schema.NestedBlockObject{
+					Attributes: map[string]schema.Attribute{
+						"type": schema.StringAttribute{
+							Required: true,
+						},
+						"title": schema.StringAttribute{
+							Required: true,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func (r *Node) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var onDeactivated, onReadDeactivated types.String
+
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("on_deactivated"), &onDeactivated)...)
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("on_read_deactivated"), &onReadDeactivated)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if onDeactivated.ValueString() == onDeactivatedAccept && onReadDeactivated.ValueString() == onReadDeactivatedRemove {
+		resp.Diagnostics.AddAttributeError(path.Root("on_read_deactivated"), "Invalid attribute combination",
+			fmt.Sprintf("on_read_deactivated cannot be %q when on_deactivated is %q, as the node would be removed from the state right after being created.", onReadDeactivatedRemove, onDeactivatedAccept))
+	}
+}
+
+func (r *Node) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if req.State.Raw.IsNull() || req.Plan.Raw.IsNull() {
+		return
+	}
+
+	var state NodeModel
+	var expectedEnvironment types.String
+
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	resp.Diagnostics.Append(req.Plan.GetAttribute(ctx, path.Root("expected_environment"), &expectedEnvironment)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	node := &puppetdb.Node{
+		CatalogEnvironment: state.CatalogEnvironment.ValueString(),
+		FactsEnvironment:   state.FactsEnvironment.ValueString(),
+		ReportEnvironment:  state.ReportEnvironment.ValueString(),
+	}
+
+	// Plan an update of the mismatched environment, which Update then
+	// reports as an error if it persists.
+	if name, _ := mismatchedEnvironment(node, expectedEnvironment.ValueString()); name != "" {
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root(name), types.StringUnknown())...)
 	}
 }
 
 func (r *Node) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
-	var plan, state NodeModel
+	var plan NodeModel
 
 	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
 
@@ -110,17 +266,50 @@ func (r *Node) Create(ctx context.Context, req resource.CreateRequest, resp *res
 
 	certificateName := plan.CertificateName.ValueString()
 
-	node, err := retryGetNode(ctx, r.provider.Client(), certificateName)
+	err := r.waitForQueueDepth(ctx, plan.MaxQueueDepth)
 
 	if err != nil {
-		resp.Diagnostics.AddError("Failed to create node", "Reason: "+err.Error())
+		resp.Diagnostics.Append(provider.ErrorDiagnostic("Failed to create node", err))
 
 		return
 	}
 
-	nodeToNodeModel(node, &state)
+	node, err := retryGetNode(ctx, r.provider.Client(), certificateName, plan.OnDeactivated.ValueString())
 
-	resp.Diagnostics.Append(resp.State.Set(ctx, state)...)
+	if err != nil {
+		resp.Diagnostics.Append(provider.ErrorDiagnostic("Failed to create node", err))
+
+		return
+	}
+
+	err = checkNodeEnvironment(node, plan.ExpectedEnvironment.ValueString())
+
+	if err != nil {
+		resp.Diagnostics.Append(provider.ErrorDiagnostic("Failed to create node", err))
+
+		return
+	}
+
+	facts, err := retryGetFacts(ctx, r.provider.Client(), certificateName, plan.WaitForFacts)
+
+	if err != nil {
+		resp.Diagnostics.Append(provider.ErrorDiagnostic("Failed to create node", err))
+
+		return
+	}
+
+	err = retryGetCatalogResources(ctx, r.provider.Client(), certificateName, plan.WaitForCatalog)
+
+	if err != nil {
+		resp.Diagnostics.Append(provider.ErrorDiagnostic("Failed to create node", err))
+
+		return
+	}
+
+	nodeToNodeModel(node, &plan)
+
+	resp.Diagnostics.Append(factsToNodeModel(ctx, facts, &plan)...)
+	resp.Diagnostics.Append(resp.State.Set(ctx, plan)...)
 }
 
 func (r *Node) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
@@ -134,6 +323,14 @@ func (r *Node) Read(ctx context.Context, req resource.ReadRequest, resp *resourc
 
 	certificateName := state.CertificateName.ValueString()
 
+	err := r.waitForQueueDepth(ctx, state.MaxQueueDepth)
+
+	if err != nil {
+		resp.Diagnostics.Append(provider.ErrorDiagnostic("Failed to read node", err))
+
+		return
+	}
+
 	node, err := getNode(ctx, r.provider.Client(), certificateName)
 
 	if err != nil {
@@ -143,13 +340,48 @@ func (r *Node) Read(ctx context.Context, req resource.ReadRequest, resp *resourc
 			return
 		}
 
-		resp.Diagnostics.AddError("Failed to read node", "Reason: "+err.Error())
+		resp.Diagnostics.Append(provider.ErrorDiagnostic("Failed to read node", err))
+
+		return
+	}
+
+	onReadDeactivated := state.OnReadDeactivated.ValueString()
+
+	// Keep nodes accepted while deactivated by default, as removing them
+	// would recreate them on every apply.
+	if state.OnReadDeactivated.IsNull() && state.OnDeactivated.ValueString() == onDeactivatedAccept {
+		onReadDeactivated = onReadDeactivatedWarn
+	}
+
+	if status := nodeInactiveStatus(node); status != "" {
+		if onReadDeactivated == onReadDeactivatedWarn {
+			resp.Diagnostics.AddWarning("Node is no longer active", fmt.Sprintf("Node %s is %s.", certificateName, status))
+		} else {
+			tflog.Info(ctx, "Removing inactive node from state", log.MergeFields(log.NodeFields(certificateName), map[string]any{
+				"status": status,
+			}))
+
+			resp.State.RemoveResource(ctx)
+
+			return
+		}
+	}
+
+	facts, err := getFacts(ctx, r.provider.Client(), certificateName, factNames(state.WaitForFacts))
+
+	if err != nil && !errors.Is(err, puppetdb.ErrNotFound) {
+		resp.Diagnostics.Append(provider.ErrorDiagnostic("Failed to read node", err))
 
 		return
 	}
 
 	nodeToNodeModel(node, &state)
 
+	if err := checkNodeEnvironment(node, state.ExpectedEnvironment.ValueString()); err != nil {
+		resp.Diagnostics.AddWarning("Node environment mismatch", fmt.Sprintf("Node %s: %s.", certificateName, err.Error()))
+	}
+
+	resp.Diagnostics.Append(factsToNodeModel(ctx, facts, &state)...)
 	resp.Diagnostics.Append(resp.State.Set(ctx, state)...)
 }
 
@@ -165,17 +397,50 @@ func (r *Node) Update(ctx context.Context, req resource.UpdateRequest, resp *res
 
 	certificateName := plan.CertificateName.ValueString()
 
-	node, err := retryGetNode(ctx, r.provider.Client(), certificateName)
+	err := r.waitForQueueDepth(ctx, plan.MaxQueueDepth)
 
 	if err != nil {
-		resp.Diagnostics.AddError("Failed to update node", "Reason: "+err.Error())
+		resp.Diagnostics.Append(provider.ErrorDiagnostic("Failed to update node", err))
 
 		return
 	}
 
-	nodeToNodeModel(node, &state)
+	node, err := retryGetNode(ctx, r.provider.Client(), certificateName, plan.OnDeactivated.ValueString())
 
-	resp.Diagnostics.Append(resp.State.Set(ctx, state)...)
+	if err != nil {
+		resp.Diagnostics.Append(provider.ErrorDiagnostic("Failed to update node", err))
+
+		return
+	}
+
+	err = checkNodeEnvironment(node, plan.ExpectedEnvironment.ValueString())
+
+	if err != nil {
+		resp.Diagnostics.Append(provider.ErrorDiagnostic("Failed to update node", err))
+
+		return
+	}
+
+	facts, err := retryGetFacts(ctx, r.provider.Client(), certificateName, plan.WaitForFacts)
+
+	if err != nil {
+		resp.Diagnostics.Append(provider.ErrorDiagnostic("Failed to update node", err))
+
+		return
+	}
+
+	err = retryGetCatalogResources(ctx, r.provider.Client(), certificateName, plan.WaitForCatalog)
+
+	if err != nil {
+		resp.Diagnostics.Append(provider.ErrorDiagnostic("Failed to update node", err))
+
+		return
+	}
+
+	nodeToNodeModel(node, &plan)
+
+	resp.Diagnostics.Append(factsToNodeModel(ctx, facts, &plan)...)
+	resp.Diagnostics.Append(resp.State.Set(ctx, plan)...)
 }
 
 func (r *Node) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
@@ -189,20 +454,72 @@ func (r *Node) Delete(ctx context.Context, req resource.DeleteRequest, resp *res
 
 	certificateName := state.CertificateName.ValueString()
 
-	err := deleteNode(ctx, r.provider.Client(), certificateName)
+	if state.OnDestroy.ValueString() == onDestroyNone {
+		tflog.Info(ctx, "Removing node from state without deactivating it", log.NodeFields(certificateName))
+
+		return
+	}
+
+	preventDestroyIfReportedWithin := r.provider.PreventDestroyIfReportedWithin()
+
+	if !state.PreventDestroyIfReportedWithin.IsNull() {
+		var err error
+
+		preventDestroyIfReportedWithin, err = time.ParseDuration(state.PreventDestroyIfReportedWithin.ValueString())
+
+		if err != nil {
+			resp.Diagnostics.Append(provider.ErrorDiagnostic("Failed to delete node", err))
+
+			return
+		}
+	}
+
+	resp.Diagnostics.Append(checkNodeNotReportedWithin(ctx, r.provider.Client(), certificateName, preventDestroyIfReportedWithin)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	result, err := deleteNode(ctx, r.provider.Client(), certificateName)
 
 	if err != nil && !errors.Is(err, puppetdb.ErrNotFound) {
-		resp.Diagnostics.AddError("Failed to delete node", "Reason: "+err.Error())
+		resp.Diagnostics.Append(provider.ErrorDiagnostic("Failed to delete node", err))
 
 		return
 	}
 
+	if result != nil && result.TimedOut {
+		resp.Diagnostics.AddWarning("Node deactivation is pending",
+			fmt.Sprintf("PuppetDB did not process the deactivation command %s for node %s within %d seconds, its command queue may be backed up. Waiting for the node to be deactivated.",
+				result.UUID, certificateName, secondsToWaitForDeactivation))
+	}
+
+	err = retryGetDeactivatedNode(ctx, r.provider.Client(), certificateName)
+
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to confirm node deactivation",
+			fmt.Sprintf("PuppetDB did not report node %s as deactivated, its command queue may be backed up. Reason: %s", certificateName, err.Error()))
+
+		return
+	}
+
+	if state.OnDestroy.ValueString() == onDestroyPurge {
+		err = purgeNode(ctx, r.provider.Client(), certificateName)
+
+		if err != nil && !errors.Is(err, puppetdb.ErrNotFound) {
+			resp.Diagnostics.Append(provider.ErrorDiagnostic("Failed to purge node", err))
+
+			return
+		}
+	}
+
 	resp.Diagnostics.Append(resp.State.Set(ctx, state)...)
 }
 
 func (r *Node) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
 	state := NodeModel{
 		CertificateName: types.StringValue(req.ID),
+		Facts:           types.MapNull(types.StringType),
 	}
 
 	resp.Diagnostics.Append(resp.State.Set(ctx, state)...)
@@ -273,6 +590,7 @@ func (r *Node) UpgradeState(ctx context.Context) map[int64]resource.StateUpgrade
 					LatestReportNoop:             oldState.LatestReportNoop,
 					LatestReportNoopPending:      oldState.LatestReportNoopPending,
 					LatestReportStatus:           oldState.LatestReportStatus,
+					Facts:                        types.MapNull(types.StringType),
 				}
 
 				resp.Diagnostics.Append(resp.State.Set(ctx, newState)...)
@@ -281,6 +599,21 @@ func (r *Node) UpgradeState(ctx context.Context) map[int64]resource.StateUpgrade
 	}
 }
 
+// waitForQueueDepth waits for the PuppetDB command queue to drain below
+// maxQueueDepth, defaulting to the provider's setting, so that recently
+// submitted facts and reports are visible.
+func (r *Node) waitForQueueDepth(ctx context.Context, maxQueueDepth types.Int64) error {
+	if maxQueueDepth.IsNull() {
+		maxQueueDepth = r.provider.MaxQueueDepth()
+	}
+
+	if maxQueueDepth.IsNull() {
+		return nil
+	}
+
+	return retryWaitForQueueDepth(ctx, r.provider.Client(), maxQueueDepth.ValueInt64())
+}
+
 func NewNode(p *provider.Provider) resource.Resource {
 	r := &Node{
 		provider: p,
@@ -288,6 +621,8 @@ func NewNode(p *provider.Provider) resource.Resource {
 
 	var _ resource.Resource = r
 	var _ resource.ResourceWithImportState = r
+	var _ resource.ResourceWithModifyPlan = r
+	var _ resource.ResourceWithValidateConfig = r
 	var _ resource.ResourceWithUpgradeState = r
 
 	return r
@@ -302,7 +637,7 @@ func getNode(ctx context.Context, client *puppetdb.Client, certificateName strin
 
 	tflog.Trace(ctx, "Requesting node", logFields)
 
-	node, err := client.Query("query/v4/nodes/"+certificateName, "GET", nil)
+	node, err := client.Query(ctx, "query/v4/nodes/"+certificateName, "GET", nil)
 
 	tflog.Trace(ctx, "Requested node", log.MergeFields(logFields, log.ErrorField(err), map[string]any{
 		"node": node,
@@ -311,44 +646,302 @@ func getNode(ctx context.Context, client *puppetdb.Client, certificateName strin
 	return node, err
 }
 
-func retryGetNode(ctx context.Context, client *puppetdb.Client, certificateName string) (*puppetdb.Node, error) {
+func retryGetNode(ctx context.Context, client *puppetdb.Client, certificateName string, onDeactivated string) (*puppetdb.Node, error) {
 	logFields := log.NodeFields(certificateName)
 
-	getNode := func() (*puppetdb.Node, error) {
+	return retry(ctx, logFields, func() (*puppetdb.Node, error) {
 		node, err := getNode(ctx, client, certificateName)
 
-		if err != nil && !errors.Is(err, puppetdb.ErrNotFound) {
-			err = backoff.Permanent(err)
-			tflog.Error(ctx, "un")
+		if err != nil {
+			if !errors.Is(err, puppetdb.ErrNotFound) {
+				err = backoff.Permanent(err)
+			}
+
+			return node, err
 		}
 
-		tflog.Error(ctx, "deux")
-		return node, err
+		if status := nodeInactiveStatus(node); status != "" {
+			switch onDeactivated {
+			case onDeactivatedAccept:
+			case onDeactivatedFail:
+				return nil, backoff.Permanent(fmt.Errorf("node %s is %s", certificateName, status))
+			default:
+				return nil, fmt.Errorf("node %s is %s, waiting for it to become active again", certificateName, status)
+			}
+		}
+
+		return node, nil
+	})
+}
+
+func getFacts(ctx context.Context, client *puppetdb.Client, certificateName string, names []string) (map[string]string, error) {
+	logFields := log.MergeFields(log.NodeFields(certificateName), log.FactsFields(names))
+
+	facts := make(map[string]string)
+
+	if len(names) == 0 {
+		return facts, nil
 	}
 
-	return backoff.RetryNotifyWithData(getNode, backoff.WithContext(backoff.NewExponentialBackOff(), ctx),
-		func(err error, delay time.Duration) {
-			tflog.Trace(ctx, "Will retry requesting node after backoff delay", log.MergeFields(logFields, log.ErrorField(err), map[string]any{
-				"delay": delay,
-			}))
-		},
-	)
+	query := []any{"or"}
+
+	for _, name := range names {
+		query = append(query, []any{"=", "name", name})
+	}
+
+	path, err := puppetdb.QueryPath("query/v4/nodes/"+certificateName+"/facts", query)
+
+	if err != nil {
+		return nil, err
+	}
+
+	tflog.Trace(ctx, "Requesting facts", logFields)
+
+	var pdbFacts []puppetdb.Fact
+
+	err = client.Do(ctx, path, "GET", nil, &pdbFacts)
+
+	tflog.Trace(ctx, "Requested facts", log.MergeFields(logFields, log.ErrorField(err), map[string]any{
+		"facts": pdbFacts,
+	}))
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, fact := range pdbFacts {
+		facts[fact.Name] = fact.ValueString()
+	}
+
+	return facts, nil
+}
+
+func retryGetFacts(ctx context.Context, client *puppetdb.Client, certificateName string, conditions []WaitForFactModel) (map[string]string, error) {
+	names := factNames(conditions)
+	logFields := log.MergeFields(log.NodeFields(certificateName), log.FactsFields(names))
+
+	return retry(ctx, logFields, func() (map[string]string, error) {
+		facts, err := getFacts(ctx, client, certificateName, names)
+
+		if err != nil {
+			if !errors.Is(err, puppetdb.ErrNotFound) {
+				err = backoff.Permanent(err)
+			}
+
+			return nil, err
+		}
+
+		for _, condition := range conditions {
+			name := condition.Name.ValueString()
+			value, ok := facts[name]
+
+			if !ok {
+				return nil, fmt.Errorf("fact %q not found", name)
+			}
+
+			if !condition.Value.IsNull() && value != condition.Value.ValueString() {
+				return nil, fmt.Errorf("fact %q is %q, expected %q", name, value, condition.Value.ValueString())
+			}
+		}
+
+		return facts, nil
+	})
+}
+
+func getCatalogResources(ctx context.Context, client *puppetdb.Client, certificateName string, conditions []WaitForCatalogModel) ([]puppetdb.Resource, error) {
+	logFields := log.MergeFields(log.NodeFields(certificateName), log.ResourcesFields(resourceReferences(conditions)))
+
+	resourcesQuery := []any{"or"}
+
+	for _, condition := range conditions {
+		resourcesQuery = append(resourcesQuery, []any{"and",
+			[]any{"=", "type", condition.Type.ValueString()},
+			[]any{"=", "title", condition.Title.ValueString()},
+		})
+	}
+
+	path, err := puppetdb.QueryPath("query/v4/resources", []any{"and",
+		[]any{"=", "certname", certificateName},
+		resourcesQuery,
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	tflog.Trace(ctx, "Requesting catalog resources", logFields)
+
+	var catalogResources []puppetdb.Resource
+
+	err = client.Do(ctx, path, "GET", nil, &catalogResources)
+
+	tflog.Trace(ctx, "Requested catalog resources", log.MergeFields(logFields, log.ErrorField(err), map[string]any{
+		"count": len(catalogResources),
+	}))
+
+	return catalogResources, err
+}
+
+func retryGetCatalogResources(ctx context.Context, client *puppetdb.Client, certificateName string, conditions []WaitForCatalogModel) error {
+	if len(conditions) == 0 {
+		return nil
+	}
+
+	logFields := log.MergeFields(log.NodeFields(certificateName), log.ResourcesFields(resourceReferences(conditions)))
+
+	_, err := retry(ctx, logFields, func() ([]puppetdb.Resource, error) {
+		catalogResources, err := getCatalogResources(ctx, client, certificateName, conditions)
+
+		if err != nil {
+			if !errors.Is(err, puppetdb.ErrNotFound) {
+				err = backoff.Permanent(err)
+			}
+
+			return nil, err
+		}
+
+		for _, condition := range conditions {
+			found := false
+
+			for _, catalogResource := range catalogResources {
+				if catalogResource.Type == condition.Type.ValueString() && catalogResource.Title == condition.Title.ValueString() {
+					found = true
+
+					break
+				}
+			}
+
+			if !found {
+				return nil, fmt.Errorf("resource %s not found in catalog", resourceReference(condition))
+			}
+		}
+
+		return catalogResources, nil
+	})
+
+	return err
 }
 
-func deleteNode(ctx context.Context, client *puppetdb.Client, certificateName string) error {
+func checkNodeNotReportedWithin(ctx context.Context, client *puppetdb.Client, certificateName string, duration time.Duration) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	if duration <= 0 {
+		return diags
+	}
+
+	node, err := getNode(ctx, client, certificateName)
+
+	if err != nil {
+		if !errors.Is(err, puppetdb.ErrNotFound) {
+			diags.Append(provider.ErrorDiagnostic("Failed to delete node", err))
+		}
+
+		return diags
+	}
+
+	if node.ReportTimestamp == "" {
+		return diags
+	}
+
+	reportTimestamp, err := time.Parse(time.RFC3339, node.ReportTimestamp)
+
+	if err != nil {
+		diags.Append(provider.ErrorDiagnostic("Failed to delete node", err))
+
+		return diags
+	}
+
+	if time.Since(reportTimestamp) < duration {
+		diags.AddError("Refusing to deactivate node",
+			fmt.Sprintf("Node %s last reported at %s, which is within the last %s.", certificateName, node.ReportTimestamp, duration))
+	}
+
+	return diags
+}
+
+func deleteNode(ctx context.Context, client *puppetdb.Client, certificateName string) (*puppetdb.CommandResult, error) {
 	logFields := log.NodeFields(certificateName)
 
 	tflog.Trace(ctx, "Requesting node deletion", logFields)
 
-	_, err := client.Query("cmd/v1", "POST", &puppetdb.Command{
+	producerTimestamp := time.Now().UTC()
+
+	result, err := client.SubmitCommand(ctx, &puppetdb.Command{
 		Command: "deactivate node",
 		Version: 3,
 		Payload: map[string]string{
-			"certname": certificateName,
+			"certname":           certificateName,
+			"producer_timestamp": producerTimestamp.Format(time.RFC3339Nano),
 		},
+	}, certificateName, producerTimestamp, secondsToWaitForDeactivation)
+
+	tflog.Trace(ctx, "Requested node deletion", log.MergeFields(logFields, log.ErrorField(err), map[string]any{
+		"result": result,
+	}))
+
+	return result, err
+}
+
+func retryWaitForQueueDepth(ctx context.Context, client *puppetdb.Client, maxQueueDepth int64) error {
+	logFields := map[string]any{
+		"max_queue_depth": maxQueueDepth,
+	}
+
+	_, err := retry(ctx, logFields, func() (int64, error) {
+		_, status, err := client.PuppetDBStatus(ctx)
+
+		if err != nil {
+			return 0, backoff.Permanent(err)
+		}
+
+		if status.QueueDepth > maxQueueDepth {
+			return status.QueueDepth, fmt.Errorf("PuppetDB command queue holds %d commands, waiting for it to drop to %d", status.QueueDepth, maxQueueDepth)
+		}
+
+		return status.QueueDepth, nil
 	})
 
-	tflog.Trace(ctx, "Requested node deletion", log.MergeFields(logFields, log.ErrorField(err)))
+	return err
+}
+
+func retryGetDeactivatedNode(ctx context.Context, client *puppetdb.Client, certificateName string) error {
+	logFields := log.NodeFields(certificateName)
+
+	_, err := retry(ctx, logFields, func() (*puppetdb.Node, error) {
+		node, err := getNode(ctx, client, certificateName)
+
+		if err != nil {
+			if errors.Is(err, puppetdb.ErrNotFound) {
+				return nil, nil
+			}
+
+			return nil, backoff.Permanent(err)
+		}
+
+		if node.Deactivated == "" {
+			return nil, fmt.Errorf("node %s is not deactivated yet", certificateName)
+		}
+
+		return node, nil
+	})
+
+	return err
+}
+
+func purgeNode(ctx context.Context, client *puppetdb.Client, certificateName string) error {
+	logFields := log.NodeFields(certificateName)
+
+	tflog.Trace(ctx, "Requesting node purge", logFields)
+
+	err := client.SubmitAdminCommand(ctx, &puppetdb.Command{
+		Command: "delete",
+		Version: 1,
+		Payload: map[string]string{
+			"certname": certificateName,
+		},
+	}, certificateName)
+
+	tflog.Trace(ctx, "Requested node purge", log.MergeFields(logFields, log.ErrorField(err)))
 
 	return err
 }
@@ -364,9 +957,88 @@ func nodeToNodeModel(node *puppetdb.Node, nodeModel *NodeModel) {
 	nodeModel.CatalogTimestamp = types.StringValue(node.CatalogTimestamp)
 	nodeModel.FactsTimestamp = types.StringValue(node.FactsTimestamp)
 	nodeModel.ReportTimestamp = types.StringValue(node.ReportTimestamp)
-	nodeModel.LatestReportCorrectiveChange = types.StringValue(node.LatestReportCorrectiveChange)
+	nodeModel.LatestReportCorrectiveChange = types.StringValue("")
+
+	// Only Puppet Enterprise reports corrective changes.
+	if node.LatestReportCorrectiveChange != nil {
+		nodeModel.LatestReportCorrectiveChange = types.StringValue(strconv.FormatBool(*node.LatestReportCorrectiveChange))
+	}
+
 	nodeModel.LatestReportHash = types.StringValue(node.LatestReportHash)
 	nodeModel.LatestReportNoop = types.BoolValue(node.LatestReportNoop)
 	nodeModel.LatestReportNoopPending = types.BoolValue(node.LatestReportNoopPending)
 	nodeModel.LatestReportStatus = types.StringValue(node.LatestReportStatus)
 }
+
+func checkNodeEnvironment(node *puppetdb.Node, expectedEnvironment string) error {
+	if name, environment := mismatchedEnvironment(node, expectedEnvironment); name != "" {
+		return fmt.Errorf("%s is %q, expected %q", name, environment, expectedEnvironment)
+	}
+
+	return nil
+}
+
+// mismatchedEnvironment returns the first of the node's environments which
+// differs from the expected one, along with its attribute name.
+func mismatchedEnvironment(node *puppetdb.Node, expectedEnvironment string) (string, string) {
+	if expectedEnvironment == "" {
+		return "", ""
+	}
+
+	for _, environment := range [][2]string{
+		{"catalog_environment", node.CatalogEnvironment},
+		{"facts_environment", node.FactsEnvironment},
+		{"report_environment", node.ReportEnvironment},
+	} {
+		if environment[1] != "" && environment[1] != expectedEnvironment {
+			return environment[0], environment[1]
+		}
+	}
+
+	return "", ""
+}
+
+// nodeInactiveStatus describes why a node is inactive, or returns an empty
+// string when the node is active.
+func nodeInactiveStatus(node *puppetdb.Node) string {
+	switch {
+	case node.Deactivated != "":
+		return "deactivated since " + node.Deactivated
+	case node.Expired != "":
+		return "expired since " + node.Expired
+	default:
+		return ""
+	}
+}
+
+func factsToNodeModel(ctx context.Context, facts map[string]string, nodeModel *NodeModel) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	nodeModel.Facts, diags = types.MapValueFrom(ctx, types.StringType, facts)
+
+	return diags
+}
+
+func factNames(conditions []WaitForFactModel) []string {
+	names := make([]string, len(conditions))
+
+	for i, condition := range conditions {
+		names[i] = condition.Name.ValueString()
+	}
+
+	return names
+}
+
+func resourceReference(condition WaitForCatalogModel) string {
+	return condition.Type.ValueString() + "[" + condition.Title.ValueString() + "]"
+}
+
+func resourceReferences(conditions []WaitForCatalogModel) []string {
+	references := make([]string, len(conditions))
+
+	for i, condition := range conditions {
+		references[i] = resourceReference(condition)
+	}
+
+	return references
+}
@@ -0,0 +1,20 @@
+package resources
+
+import (
+	"context"
+	"time"
+
+	"github.com/camptocamp/terraform-provider-puppetdb/internal/log"
+	"github.com/cenkalti/backoff/v4"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+func retry[T any](ctx context.Context, logFields map[string]any, operation backoff.OperationWithData[T]) (T, error) {
+	return backoff.RetryNotifyWithData(operation, backoff.WithContext(backoff.NewExponentialBackOff(), ctx),
+		func(err error, delay time.Duration) {
+			tflog.Trace(ctx, "Will retry after backoff delay", log.MergeFields(logFields, log.ErrorField(err), map[string]any{
+				"delay": delay,
+			}))
+		},
+	)
+}